@@ -104,8 +104,11 @@ func GetAlerts(c *models.ReqContext) Response {
 		DashboardIDs: dashboardIDs,
 		PanelId:      c.QueryInt64("panelId"),
 		Limit:        c.QueryInt64("limit"),
+		Offset:       c.QueryInt64("offset"),
 		User:         c.SignedInUser,
 		Query:        c.Query("query"),
+		SortBy:       c.Query("sortBy"),
+		SortDesc:     c.QueryBool("sortDesc"),
 	}
 
 	states := c.QueryStrings("state")
@@ -114,6 +117,9 @@ func GetAlerts(c *models.ReqContext) Response {
 	}
 
 	if err := bus.Dispatch(&query); err != nil {
+		if err == models.ErrInvalidAlertSortBy {
+			return Error(400, err.Error(), err)
+		}
 		return Error(500, "List alerts failed", err)
 	}
 
@@ -462,9 +468,11 @@ func PauseAlert(c *models.ReqContext, dto dtos.PauseAlertCommand) Response {
 	}
 
 	cmd := models.PauseAlertCommand{
-		OrgId:    c.OrgId,
-		AlertIds: []int64{alertID},
-		Paused:   dto.Paused,
+		OrgId:       c.OrgId,
+		AlertIds:    []int64{alertID},
+		Paused:      dto.Paused,
+		PauseReason: dto.Reason,
+		PausedBy:    c.UserId,
 	}
 
 	if err := bus.Dispatch(&cmd); err != nil {
@@ -486,7 +494,9 @@ func PauseAlert(c *models.ReqContext, dto dtos.PauseAlertCommand) Response {
 //POST /api/admin/pause-all-alerts
 func PauseAllAlerts(c *models.ReqContext, dto dtos.PauseAllAlertsCommand) Response {
 	updateCmd := models.PauseAllAlertCommand{
-		Paused: dto.Paused,
+		Paused:      dto.Paused,
+		PauseReason: dto.Reason,
+		PausedBy:    c.UserId,
 	}
 
 	if err := bus.Dispatch(&updateCmd); err != nil {
@@ -143,10 +143,12 @@ type NotificationTestCommand struct {
 }
 
 type PauseAlertCommand struct {
-	AlertId int64 `json:"alertId"`
-	Paused  bool  `json:"paused"`
+	AlertId int64  `json:"alertId"`
+	Paused  bool   `json:"paused"`
+	Reason  string `json:"reason"`
 }
 
 type PauseAllAlertsCommand struct {
-	Paused bool `json:"paused"`
+	Paused bool   `json:"paused"`
+	Reason string `json:"reason"`
 }
@@ -2,6 +2,7 @@ package models
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/grafana/grafana/pkg/components/simplejson"
@@ -36,6 +37,15 @@ const (
 var (
 	ErrCannotChangeStateOnPausedAlert = fmt.Errorf("Cannot change state on pause alert")
 	ErrRequiresNewState               = fmt.Errorf("update alert state requires a new state")
+	ErrInvalidAlertFrequency          = fmt.Errorf("alert frequency must be greater than zero")
+	ErrAlertNamePrefixRequired        = fmt.Errorf("alert name prefix is required")
+	ErrAlertDeleteSafetyCapExceeded   = fmt.Errorf("number of alerts to delete exceeds the safety cap")
+	ErrInvalidAlertPermissionLevel    = fmt.Errorf("permission level must be view, edit or admin")
+	ErrInvalidAlertSortBy             = fmt.Errorf("alerts can only be sorted by name, state, new_state_date or dashboard")
+	ErrInvalidExecutionErrorState     = fmt.Errorf("invalid execution error state")
+	ErrAlertNotFound                  = fmt.Errorf("alert not found")
+	ErrInvalidConditionOperator       = fmt.Errorf("condition operator must be and or or")
+	ErrAlertNamingTagKeyRequired      = fmt.Errorf("tag key of the naming rule is required")
 )
 
 func (s AlertStateType) IsValid() bool {
@@ -63,6 +73,40 @@ func (s ExecutionErrorOption) ToAlertState() AlertStateType {
 	return AlertStateType(s)
 }
 
+// AlertNameMaxLength is the size of the alert name column and the longest
+// name the UI handles.
+const AlertNameMaxLength = 255
+
+type AlertExecutionErrorCategory string
+
+const (
+	ExecutionErrorCategoryTimeout    AlertExecutionErrorCategory = "timeout"
+	ExecutionErrorCategoryConnection AlertExecutionErrorCategory = "connection"
+	ExecutionErrorCategoryOther      AlertExecutionErrorCategory = "other"
+)
+
+var executionErrorCategoryPatterns = []struct {
+	pattern  string
+	category AlertExecutionErrorCategory
+}{
+	{"context deadline exceeded", ExecutionErrorCategoryTimeout},
+	{"i/o timeout", ExecutionErrorCategoryTimeout},
+	{"dial tcp", ExecutionErrorCategoryConnection},
+	{"connection refused", ExecutionErrorCategoryConnection},
+	{"no such host", ExecutionErrorCategoryConnection},
+}
+
+// CategorizeExecutionError maps an alert execution error to a category based
+// on known error messages.
+func CategorizeExecutionError(executionError string) AlertExecutionErrorCategory {
+	for _, p := range executionErrorCategoryPatterns {
+		if strings.Contains(executionError, p.pattern) {
+			return p.category
+		}
+	}
+	return ExecutionErrorCategoryOther
+}
+
 type Alert struct {
 	Id             int64
 	Version        int64
@@ -78,6 +122,9 @@ type Alert struct {
 	ExecutionError string
 	Frequency      int64
 	For            time.Duration
+	EvalTimeout    time.Duration
+	PauseReason    string
+	PausedBy       int64
 
 	EvalData     *simplejson.Json
 	NewStateDate time.Time
@@ -132,6 +179,97 @@ func (alert *Alert) GetTagsFromSettings() []*Tag {
 	return tags
 }
 
+// AlertNotificationRef is a reference to a notification channel as stored
+// in the alert settings. Older alerts reference channels by id, newer by uid.
+type AlertNotificationRef struct {
+	Id  int64  `json:"id,omitempty"`
+	Uid string `json:"uid,omitempty"`
+}
+
+// Matches reports whether the reference points to the given notification channel.
+func (ref *AlertNotificationRef) Matches(notification *AlertNotification) bool {
+	if ref.Uid != "" {
+		return ref.Uid == notification.Uid
+	}
+	return ref.Id != 0 && ref.Id == notification.Id
+}
+
+func (alert *Alert) GetNotificationsFromSettings() []*AlertNotificationRef {
+	refs := []*AlertNotificationRef{}
+	if alert.Settings != nil {
+		for _, v := range alert.Settings.Get("notifications").MustArray() {
+			jsonModel := simplejson.NewFromAny(v)
+			if id, err := jsonModel.Get("id").Int64(); err == nil {
+				refs = append(refs, &AlertNotificationRef{Id: id})
+			} else if uid, err := jsonModel.Get("uid").String(); err == nil {
+				refs = append(refs, &AlertNotificationRef{Uid: uid})
+			}
+		}
+	}
+	return refs
+}
+
+// AlertFilter selects alerts by properties that are available on the
+// alert itself. An empty filter matches every alert.
+type AlertFilter struct {
+	// Query matches alerts whose name contains it, ignoring case
+	Query        string
+	DashboardIds []int64
+	States       []AlertStateType
+	// Tags matches alerts carrying all of the tags
+	Tags []*Tag
+}
+
+func (f *AlertFilter) Matches(alert *Alert) bool {
+	if f.Query != "" && !strings.Contains(strings.ToLower(alert.Name), strings.ToLower(f.Query)) {
+		return false
+	}
+
+	if len(f.DashboardIds) > 0 {
+		found := false
+		for _, id := range f.DashboardIds {
+			if id == alert.DashboardId {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.States) > 0 {
+		found := false
+		for _, state := range f.States {
+			if state == alert.State {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.Tags) > 0 {
+		alertTags := alert.GetTagsFromSettings()
+		for _, tag := range f.Tags {
+			found := false
+			for _, alertTag := range alertTags {
+				if alertTag.Key == tag.Key && alertTag.Value == tag.Value {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
 type AlertingClusterInfo struct {
 	ServerId       string
 	ClusterSize    int
@@ -155,6 +293,11 @@ type SaveAlertsCommand struct {
 	UserId      int64
 	OrgId       int64
 
+	// PreserveState inserts new alerts with the state they have in Alerts
+	// instead of unknown, e.g. for provisioning tools keeping track of the
+	// state themselves. Alerts without a valid state are still unknown.
+	PreserveState bool
+
 	Alerts []*Alert
 }
 
@@ -163,11 +306,113 @@ type PauseAlertCommand struct {
 	AlertIds    []int64
 	ResultCount int64
 	Paused      bool
+	PauseReason string
+	PausedBy    int64
 }
 
 type PauseAllAlertCommand struct {
 	ResultCount int64
 	Paused      bool
+	PauseReason string
+	PausedBy    int64
+}
+
+type AlertFrequencyTier struct {
+	Filter    AlertFilter
+	Frequency int64
+}
+
+// SetAlertFrequencyByTierCommand sets the frequency of the alerts of an org
+// from the first tier whose filter matches the alert, in the alert, its
+// frequency setting and the alert json of its panel. Result holds the
+// number of alerts updated per tier.
+type SetAlertFrequencyByTierCommand struct {
+	OrgId int64
+	Tiers []*AlertFrequencyTier
+
+	Result []int64
+}
+
+// DeleteAlertsByNamePrefixCommand deletes the alerts of an org whose name
+// starts with Prefix. Nothing is deleted if more alerts than SafetyCap
+// (500 when not set) match. With DryRun only WouldDeleteCount is set, even
+// above the safety cap.
+type DeleteAlertsByNamePrefixCommand struct {
+	OrgId     int64
+	Prefix    string
+	DryRun    bool
+	SafetyCap int64
+
+	DeletedCount     int64
+	WouldDeleteCount int64
+}
+
+// SilenceAlertsByFilterCommand silences, or unsilences, the alerts of an org
+// matching the filters of Filter. Evaluation of the alerts continues, only
+// their notifications are not sent.
+type SilenceAlertsByFilterCommand struct {
+	OrgId    int64
+	Filter   *GetAlertsQuery
+	Silenced bool
+
+	ResultCount int64
+}
+
+// SetExecutionErrorStateCommand sets the executionErrorState setting of the
+// alerts of an org matching the filters of Filter, and of their panels.
+type SetExecutionErrorStateCommand struct {
+	OrgId               int64
+	Filter              *GetAlertsQuery
+	ExecutionErrorState ExecutionErrorOption
+
+	ResultCount int64
+}
+
+// PurgeDanglingAlertNotificationsCommand removes the references to
+// notification channels that no longer exist from the settings of the
+// alerts of an org.
+type PurgeDanglingAlertNotificationsCommand struct {
+	OrgId int64
+
+	ResultCount int64
+}
+
+// ImportAlertTagsCommand replaces the tags of alerts with the tags mapped to
+// their id. Result holds one entry per alert id, ordered by id.
+type ImportAlertTagsCommand struct {
+	OrgId int64
+	Tags  map[int64][]*Tag
+
+	Result []*ImportAlertTagsResult
+}
+
+type ImportAlertTagsResult struct {
+	AlertId  int64  `json:"alertId"`
+	TagCount int    `json:"tagCount"`
+	Error    string `json:"error,omitempty"`
+}
+
+// RenameAlertTagValueCommand changes the value of the Key tag of the
+// alerts of an org from OldValue to NewValue, e.g. after a team rename.
+type RenameAlertTagValueCommand struct {
+	OrgId    int64
+	Key      string
+	OldValue string
+	NewValue string
+
+	ResultCount int64
+}
+
+// ReplaceNotificationChannelCommand makes the alerts of an org sending to
+// the channel with OldUid send to the channel with NewUid instead. A
+// non-nil Filter restricts the alerts changed to the ones matching it.
+type ReplaceNotificationChannelCommand struct {
+	OrgId  int64
+	OldUid string
+	NewUid string
+	Filter *GetAlertsQuery
+
+	ResultCount int64
 }
 
 type SetAlertStateCommand struct {
@@ -180,7 +425,7 @@ type SetAlertStateCommand struct {
 	Result Alert
 }
 
-//Queries
+// Queries
 type GetAlertsQuery struct {
 	OrgId        int64
 	State        []string
@@ -190,9 +435,605 @@ type GetAlertsQuery struct {
 	Query        string
 	User         *SignedInUser
 
+	// MessageQuery matches alerts whose message contains it, like Query does
+	// for the name. The leading wildcard keeps the database from using an
+	// index, so every alert of the org is scanned.
+	MessageQuery string
+
+	// SortBy is one of "name" (default), "state", "new_state_date" or
+	// "dashboard", which sorts alerts by dashboard and panel.
+	// Alerts sorted the same are sorted by name. SortDesc reverses the order.
+	SortBy   string
+	SortDesc bool
+
+	// MaxFrequency limits the result to alerts evaluating at least every
+	// MaxFrequency seconds
+	MaxFrequency int64
+
+	// TeamId limits the result to alerts on dashboards the team can edit
+	// through the permissions granted to it on the dashboard or its folder
+	TeamId int64
+
+	// GeneralFolderOnly limits the result to alerts on dashboards in the General folder
+	GeneralFolderOnly bool
+	// DashboardSlugLike matches the slug of the alert's dashboard against a
+	// pattern where * matches any characters, e.g. svc-*-prod
+	DashboardSlugLike string
+
+	// NoSeries limits the result to alerts whose last state change found no
+	// series. This is a heuristic on the JSON stored as eval data and only
+	// reflects the evaluation that caused the last state change.
+	NoSeries bool
+	// Tags are key:value pairs the alerts have to carry, all of them unless
+	// TagsMatchAny is set
+	Tags         []string
+	TagsMatchAny bool
+	// ReducerType and EvaluatorType match alerts with a condition using
+	// that reducer (e.g. avg) and evaluator (e.g. gt). Both have to be used
+	// by the same condition. Filtering on them decodes the settings of
+	// every alert of the org.
+	ReducerType   string
+	EvaluatorType string
+
+	// Offset skips that many alerts of the result. Without a Limit at most
+	// 1000 alerts are returned, since most databases need a limit for an
+	// offset. Page takes precedence over Offset.
+	Offset int64
+
+	// Page selects a page of the result and takes precedence over Limit.
+	// Paging is set only when Page is.
+	Page *AlertsPage
+	// WithStateCounts also counts the alerts matching the filters per
+	// state, ignoring Page and Limit
+	WithStateCounts bool
+
+	Result      []*AlertListItemDTO
+	Paging      *AlertsPaging
+	StateCounts map[AlertStateType]int64
+}
+
+// AlertsPage selects a page of alerts. Pages are numbered from 1.
+type AlertsPage struct {
+	PageNumber int64
+	PageSize   int64
+}
+
+type AlertsPaging struct {
+	Total      int64 `json:"total"`
+	Page       int64 `json:"page"`
+	PageSize   int64 `json:"pageSize"`
+	TotalPages int64 `json:"totalPages"`
+}
+
+// GetAlertFrequencyViolationsQuery finds alerts evaluating more often than
+// MaxAllowedFrequency (in seconds) allows.
+type GetAlertFrequencyViolationsQuery struct {
+	OrgId               int64
+	MaxAllowedFrequency int64
+
+	Result []*AlertListItemDTO
+}
+
+// GetAlertDependencyCountsQuery counts the rows referencing each of the
+// given alerts in the tables that are cleaned up when an alert is deleted.
+type GetAlertDependencyCountsQuery struct {
+	OrgId    int64
+	AlertIds []int64
+
+	Result []*AlertDependencyCountsDTO
+}
+
+// GetAlertsByNotificationUIDCountQuery counts the alerts of an org that
+// send to the notification channel with the given uid.
+type GetAlertsByNotificationUIDCountQuery struct {
+	OrgId           int64
+	NotificationUID string
+
+	AlertCount int64
+	AlertIds   []int64
+}
+
+// GetAlertsByConditionQueryParamsQuery finds alerts with a condition whose
+// query param at ParamIndex equals ParamValue, e.g. index 0 for the metric
+// path of a Graphite query.
+type GetAlertsByConditionQueryParamsQuery struct {
+	OrgId      int64
+	ParamIndex int
+	ParamValue string
+
+	Result []*AlertListItemDTO
+}
+
+// GetAlertsByEvalDataSizeQuery finds the alerts whose eval data is at least
+// MinBytes large, largest first.
+type GetAlertsByEvalDataSizeQuery struct {
+	OrgId    int64
+	MinBytes int64
+
+	Result []*AlertEvalDataSizeDTO
+}
+
+// GetAlertsBySettingsSizeQuery finds the alerts whose settings are at least
+// MinBytes large, largest first.
+type GetAlertsBySettingsSizeQuery struct {
+	OrgId    int64
+	MinBytes int64
+
+	Result []*AlertSettingsSizeDTO
+}
+
+// GetAlertsByNotificationTypeQuery finds the alerts sending to any
+// notification channel of the given type, e.g. "hipchat".
+type GetAlertsByNotificationTypeQuery struct {
+	OrgId int64
+	Type  string
+
+	Result []*AlertListItemDTO
+}
+
+// GetAlertsByOrgAndStateCursorQuery returns a page of alerts with an id
+// greater than AfterAlertId. NextCursor is the AfterAlertId of the next page,
+// or 0 when there are no more alerts.
+type GetAlertsByOrgAndStateCursorQuery struct {
+	OrgId        int64
+	States       []AlertStateType
+	AfterAlertId int64
+	Limit        int
+
+	Result     []*AlertListItemDTO
+	NextCursor int64
+}
+
+// GetAlertsByOrgAndNotPausedQuery returns up to MaxAlertsPerFetch non-paused
+// alerts with an id greater than AfterAlertId. NextCursor is the
+// AfterAlertId of the next chunk, or 0 when there are no more alerts.
+type GetAlertsByOrgAndNotPausedQuery struct {
+	OrgId             int64
+	MaxAlertsPerFetch int64
+	AfterAlertId      int64
+
+	Result     []*Alert
+	NextCursor int64
+}
+
+// GetAlertsModifiedRecentlyQuery returns up to Limit alerts updated within
+// the last Since, most recently updated first. OldestUpdatedAt is the update
+// time of the last alert returned, zero when there is none.
+type GetAlertsModifiedRecentlyQuery struct {
+	OrgId int64
+	Since time.Duration
+	Limit int64
+
+	Result          []*Alert
+	OldestUpdatedAt time.Time
+}
+
+// GetAlertsByExecutionErrorTypeQuery groups the alerts of an org that
+// failed to execute by the category of their execution error. ErrorPattern
+// optionally restricts the alerts to errors containing it.
+type GetAlertsByExecutionErrorTypeQuery struct {
+	OrgId        int64
+	ErrorPattern string
+
+	Result []*AlertExecutionErrorGroupDTO
+}
+
+// GetAlertStatesAtQuery reconstructs the state of the alerts of an org at a
+// point in time from the state change annotations. Alerts without a state
+// change before At are left out.
+type GetAlertStatesAtQuery struct {
+	OrgId int64
+	At    time.Time
+
+	Result []*AlertStateAtDTO
+}
+
+// GetAlertsByForZeroQuery finds the alerts without a For duration, which
+// fire on the first failing evaluation.
+type GetAlertsByForZeroQuery struct {
+	OrgId         int64
+	IncludePaused bool
+
+	Result []*AlertListItemDTO
+}
+
+// GetAlertsByEvalDataNullQuery finds the alerts without eval data. Eval
+// data is only written on state changes, so these are the alerts that have
+// not changed state since they were created, e.g. not been evaluated yet.
+type GetAlertsByEvalDataNullQuery struct {
+	OrgId         int64
+	IncludePaused bool
+
+	Result []*AlertListItemDTO
+}
+
+// GetAlertsByDashboardPermissionQuery lists the alerts on dashboards the user
+// has at least PermissionLevel on, e.g. PERMISSION_EDIT for the alerts the
+// user may edit.
+type GetAlertsByDashboardPermissionQuery struct {
+	OrgId           int64
+	User            *SignedInUser
+	PermissionLevel PermissionType
+
+	Result []*AlertListItemDTO
+}
+
+// GetOldestFiringAlertQuery returns the alert of an org that has been in
+// the alerting state the longest, or nil if no alert is firing.
+type GetOldestFiringAlertQuery struct {
+	OrgId int64
+
+	Result         *AlertListItemDTO
+	FiringDuration time.Duration
+}
+
+// GetAlertsByNotificationCountQuery finds the alerts sending to between
+// MinNotifications and MaxNotifications channels, both inclusive. Setting
+// both to zero finds the alerts that notify nobody.
+type GetAlertsByNotificationCountQuery struct {
+	OrgId            int64
+	MinNotifications int
+	MaxNotifications int
+
+	Result []*AlertNotificationCountDTO
+}
+
+// GetAlertsByCreatedAtQuery finds the alerts created on the day of Date.
+// The time of day is ignored and the dates are compared in the timezone of
+// the database server, alerts created close to midnight can fall on another
+// day than in the location of Date.
+type GetAlertsByCreatedAtQuery struct {
+	OrgId int64
+	Date  time.Time
+
+	Result []*AlertListItemDTO
+}
+
+// GetUnmonitoredServicesQuery finds the services, identified by the value
+// of the TagKey tag, whose alerts are all paused.
+type GetUnmonitoredServicesQuery struct {
+	OrgId  int64
+	TagKey string
+
+	Result []*UnmonitoredServiceDTO
+}
+
+// GetAlertsBeyondFrequencyCapQuery finds the alerts evaluating faster than
+// FrequencyCap (in seconds) allows.
+type GetAlertsBeyondFrequencyCapQuery struct {
+	OrgId        int64
+	FrequencyCap int64
+
+	Result []*AlertListItemDTO
+}
+
+// GetAlertsByTagCountQuery finds the alerts with between MinTags and MaxTags
+// tags, both inclusive. Setting both to zero finds untagged alerts.
+type GetAlertsByTagCountQuery struct {
+	OrgId   int64
+	MinTags int
+	MaxTags int
+
+	Result []*AlertTagCountDTO
+}
+
+// GetAlertsByForDurationDistributionQuery counts the alerts of an org per
+// For duration bucket. Buckets are cumulative: each one counts the alerts
+// with a For duration at or below its upper bound.
+type GetAlertsByForDurationDistributionQuery struct {
+	OrgId   int64
+	Buckets []time.Duration
+
+	Result []*ForDurationBucket
+}
+
+// GetAlertsByNameLengthQuery finds the alerts whose name is between
+// MinLength and MaxLength characters long, both inclusive.
+type GetAlertsByNameLengthQuery struct {
+	OrgId     int64
+	MinLength int
+	MaxLength int
+
+	Result []*AlertNameLengthDTO
+}
+
+// GetAlertsByStateChangeSinceQuery finds the alerts whose state changed at
+// or after Since, optionally restricted to States.
+type GetAlertsByStateChangeSinceQuery struct {
+	OrgId  int64
+	Since  time.Time
+	States []AlertStateType
+	Limit  int64
+
+	Result []*AlertListItemDTO
+}
+
+// GetAlertDetailQuery loads an alert together with its dashboard, tags and
+// notification channels.
+type GetAlertDetailQuery struct {
+	Id    int64
+	OrgId int64
+
+	Result *AlertDetailDTO
+}
+
+// GetAlertsByMultipleDashboardUidsQuery finds the alerts of the dashboards
+// with the given uids. Result is keyed by dashboard uid and UnresolvedUIDs
+// lists the uids that match no dashboard of the org.
+type GetAlertsByMultipleDashboardUidsQuery struct {
+	OrgId         int64
+	DashboardUIDs []string
+
+	Result         map[string][]*Alert
+	UnresolvedUIDs []string
+}
+
+// GetAlertingCapacityForecastQuery estimates the evaluation load of the
+// alerts of an org over each of the ForecastIntervals, starting now.
+type GetAlertingCapacityForecastQuery struct {
+	OrgId             int64
+	ForecastIntervals []time.Duration
+
+	Result []*CapacityForecast
+}
+
+// GetAlertsWithDanglingNotificationsQuery finds the alerts referencing
+// notification channels that no longer exist.
+type GetAlertsWithDanglingNotificationsQuery struct {
+	OrgId int64
+
+	Result []*AlertDanglingNotificationsDTO
+}
+
+// GetAlertsByStateAndAgeQuery finds the alerts that have been in State for
+// longer than OlderThan. With AutoPause set, the alerts found are paused.
+// Result holds the alerts as they were before pausing.
+type GetAlertsByStateAndAgeQuery struct {
+	OrgId     int64
+	State     AlertStateType
+	OlderThan time.Duration
+	AutoPause bool
+
+	Result []*AlertListItemDTO
+}
+
+// GetAlertsForChannelGroupQuery finds the alerts sending to any of the
+// notification channels with the given uids.
+type GetAlertsForChannelGroupQuery struct {
+	OrgId       int64
+	ChannelUids []string
+
+	Result []*AlertChannelGroupDTO
+}
+
+// GetAlertsByPanelQuery finds the alerts on the panel with PanelId on any
+// dashboard, e.g. across dashboards created from the same template.
+type GetAlertsByPanelQuery struct {
+	OrgId   int64
+	PanelId int64
+
+	Result []*AlertListItemDTO
+}
+
+// GetAlertsWithSharedPanelIdQuery finds the panel ids that alerts on
+// different dashboards have in common, usually after panels were copied
+// between dashboards.
+type GetAlertsWithSharedPanelIdQuery struct {
+	OrgId int64
+
+	Result []*AlertSharedPanelDTO
+}
+
+// GetEvaluationSchedulePreviewQuery finds the alerts due for evaluation
+// within Window from now, one minute when Window is not set.
+type GetEvaluationSchedulePreviewQuery struct {
+	OrgId  int64
+	Window time.Duration
+
+	DueCount    int64
+	DueAlertIds []int64
+}
+
+// GetAlertsBySettingsKeyQuery finds the alerts whose settings have the top
+// level Key, e.g. to find alerts still using a deprecated option.
+type GetAlertsBySettingsKeyQuery struct {
+	OrgId int64
+	Key   string
+
+	Result []*AlertListItemDTO
+}
+
+// GetOrgsWithAlertStateCountAboveThresholdQuery finds the orgs with at
+// least Threshold alerts in State.
+type GetOrgsWithAlertStateCountAboveThresholdQuery struct {
+	State     AlertStateType
+	Threshold int64
+
+	Result []*OrgAlertStateCount
+}
+
+// GetAlertNamesForDashboardQuery loads only the id, name and panel of the
+// alerts of a dashboard.
+type GetAlertNamesForDashboardQuery struct {
+	OrgId       int64
+	DashboardId int64
+
+	Result []*AlertNameDTO
+}
+
+// GetAlertsByNewStateDateHourQuery counts the alerts per hour in which
+// their state last changed, between From (inclusive) and To (exclusive).
+// Only the last state change of each alert is known.
+type GetAlertsByNewStateDateHourQuery struct {
+	OrgId int64
+	From  time.Time
+	To    time.Time
+
+	Result []*AlertStateHourBucket
+}
+
+// GetFiringByHourOfDayQuery counts the transitions to alerting between From
+// and To per hour of the day. Result always has 24 entries, one per hour in
+// UTC.
+type GetFiringByHourOfDayQuery struct {
+	OrgId int64
+	From  time.Time
+	To    time.Time
+
+	Result []*AlertHourOfDayCount
+}
+
+// GetAlertsByConditionOperatorQuery finds the multi-condition alerts that
+// combine conditions with Operator, either "and" or "or".
+type GetAlertsByConditionOperatorQuery struct {
+	OrgId    int64
+	Operator string
+
+	Result []*AlertListItemDTO
+}
+
+// GetAlertNamingViolationsQuery finds the alerts whose name does not start
+// with the value of their TagKey tag, e.g. the service they belong to.
+// Alerts without a value for TagKey are violations with no expected prefix.
+type GetAlertNamingViolationsQuery struct {
+	OrgId  int64
+	TagKey string
+
+	Result []*AlertNamingViolationDTO
+}
+
+// GetAlertsByEvalDataValueQuery finds the alerts whose last state change
+// matched MetricName with a value greater than GtValue and less than
+// LtValue. A nil bound is not checked.
+type GetAlertsByEvalDataValueQuery struct {
+	OrgId      int64
+	MetricName string
+	GtValue    *float64
+	LtValue    *float64
+
+	Result []*AlertEvalDataValueDTO
+}
+
+// GetAlertsByOrgFiringDurationQuery finds the alerting alerts of an org
+// with how long they have been firing. The longest firing alert comes first.
+type GetAlertsByOrgFiringDurationQuery struct {
+	OrgId int64
+
+	Result []*AlertFiringDurationDTO
+}
+
+// GetAlertsWithBlankNameQuery finds the alerts whose name is empty or only
+// spaces.
+type GetAlertsWithBlankNameQuery struct {
+	OrgId int64
+
+	Result []*AlertListItemDTO
+}
+
+// GetDuplicateAlertsOnDashboardQuery finds the groups of alerts on a
+// dashboard with identical name and settings.
+type GetDuplicateAlertsOnDashboardQuery struct {
+	OrgId       int64
+	DashboardId int64
+
+	Result []*DuplicateAlertGroupDTO
+}
+
+// CompareOrgAlertsQuery compares the alerts of two orgs, e.g. staging and
+// production, matching them by name. Datasource ids and notification ids,
+// which are specific to an org, are ignored.
+type CompareOrgAlertsQuery struct {
+	OrgIdA int64
+	OrgIdB int64
+
+	Result *OrgAlertsDriftDTO
+}
+
+// GetAlertsByTagCountDistributionQuery counts the alerts of an org per
+// number of tags.
+type GetAlertsByTagCountDistributionQuery struct {
+	OrgId int64
+
+	Result []*TagCountBucket
+}
+
+// GetAlertsByPauseReasonQuery finds the alerts paused with a reason
+// containing PauseReason, or all paused alerts when it is empty, along with
+// who paused them.
+type GetAlertsByPauseReasonQuery struct {
+	OrgId       int64
+	PauseReason string
+
+	Result []*AlertPauseInfoDTO
+}
+
+// GetAlertsWithFewEvaluationsInForQuery finds the alerts with a For duration
+// that is evaluated fewer than MinEvaluations times before the alert fires.
+// Such alerts fire after only a couple of evaluations, which is easy to miss
+// when the frequency is close to the For duration.
+type GetAlertsWithFewEvaluationsInForQuery struct {
+	OrgId          int64
+	MinEvaluations int64
+
+	Result []*AlertEvaluationsInForDTO
+}
+
+// ExportAlertsByFilterQuery exports the alerts of an org matching Filter in
+// a form that does not depend on ids of the exporting instance. A nil Filter
+// exports every alert of the org.
+type ExportAlertsByFilterQuery struct {
+	OrgId  int64
+	Filter *GetAlertsQuery
+
+	Result []*AlertExportDTO
+}
+
+// GetAlertCountByDashboardQuery counts the alerts of the given dashboards
+// per state. Result is keyed by dashboard id and only has entries for
+// dashboards with alerts.
+type GetAlertCountByDashboardQuery struct {
+	OrgId        int64
+	DashboardIds []int64
+
+	Result map[int64]map[AlertStateType]int64
+}
+
+// GetOverduePendingAlertsQuery finds the pending alerts that have been
+// pending for longer than their For duration. Those should have started
+// alerting already, so they point at a stalled scheduler.
+type GetOverduePendingAlertsQuery struct {
+	OrgId int64
+
+	Result []*AlertOverduePendingDTO
+}
+
+// GetAlertsByAnnotationCountQuery finds the alerts with at least
+// MinAnnotations annotations, most annotations first. Alerts that change
+// state often account for most of the annotation table.
+type GetAlertsByAnnotationCountQuery struct {
+	OrgId          int64
+	MinAnnotations int64
+
+	Result []*AlertAnnotationCountDTO
+}
+
+// GetAlertsCreatedRecentlyQuery finds the alerts created within the last
+// Since, newest first, e.g. to watch the alerts added by a deployment.
+type GetAlertsCreatedRecentlyQuery struct {
+	OrgId int64
+	Since time.Duration
+
 	Result []*AlertListItemDTO
 }
 
+// GetDatasourceAlertErrorRatesQuery counts per data source the alerts
+// querying it and how many of them currently have an execution error.
+type GetDatasourceAlertErrorRatesQuery struct {
+	OrgId int64
+
+	Result []*DatasourceAlertErrorRateDTO
+}
+
 type GetAllAlertsQuery struct {
 	Result []*Alert
 }
@@ -210,6 +1051,26 @@ type GetAlertStatesForDashboardQuery struct {
 	Result []*AlertStateInfoDTO
 }
 
+// GetAlertsByDashboardAndStateQuery is like GetAlertStatesForDashboardQuery
+// but only returns the alerts in one of the given states. Without states
+// all alerts on the dashboard are returned. Results are ordered by panel id.
+type GetAlertsByDashboardAndStateQuery struct {
+	OrgId       int64
+	DashboardId int64
+	State       []AlertStateType
+
+	Result []*AlertStateInfoDTO
+}
+
+// GetAlertStatesForPanelsQuery loads the states of the alerts on the panels
+// with the given ids, on any dashboard of the org.
+type GetAlertStatesForPanelsQuery struct {
+	OrgId    int64
+	PanelIds []int64
+
+	Result []*AlertStateInfoDTO
+}
+
 type AlertListItemDTO struct {
 	Id             int64            `json:"id"`
 	DashboardId    int64            `json:"dashboardId"`
@@ -225,6 +1086,213 @@ type AlertListItemDTO struct {
 	Url            string           `json:"url"`
 }
 
+type AlertDependencyCountsDTO struct {
+	AlertId            int64 `json:"alertId"`
+	Annotations        int64 `json:"annotations"`
+	AlertRuleTags      int64 `json:"alertRuleTags"`
+	NotificationStates int64 `json:"notificationStates"`
+}
+
+type AlertEvalDataSizeDTO struct {
+	AlertListItemDTO `xorm:"extends"`
+	EvalDataBytes    int64 `json:"evalDataBytes"`
+}
+
+type AlertSettingsSizeDTO struct {
+	AlertListItemDTO  `xorm:"extends"`
+	SettingsSizeBytes int64 `json:"settingsSizeBytes"`
+}
+
+type AlertPauseInfoDTO struct {
+	AlertListItemDTO `xorm:"extends"`
+	PauseReason      string `json:"pauseReason"`
+	PausedBy         int64  `json:"pausedBy"`
+}
+
+type AlertEvaluationsInForDTO struct {
+	AlertListItemDTO `xorm:"extends"`
+	Frequency        int64         `json:"frequency"`
+	ForDuration      time.Duration `json:"for"`
+	EvaluationsInFor int64         `json:"evaluationsInFor"`
+}
+
+type AlertOverduePendingDTO struct {
+	AlertListItemDTO `xorm:"extends"`
+	ForDuration      time.Duration `json:"for"`
+	// Overdue is how long ago the alert should have started alerting
+	Overdue time.Duration `json:"overdue" xorm:"-"`
+}
+
+type AlertAnnotationCountDTO struct {
+	AlertListItemDTO `xorm:"extends"`
+	AnnotationCount  int64 `json:"annotationCount"`
+}
+
+type DatasourceAlertErrorRateDTO struct {
+	DatasourceId   int64   `json:"datasourceId"`
+	DatasourceName string  `json:"datasourceName"`
+	AlertCount     int64   `json:"alertCount"`
+	ErroringCount  int64   `json:"erroringCount"`
+	ErrorRate      float64 `json:"errorRate"`
+}
+
+type AlertExecutionErrorGroupDTO struct {
+	Category     AlertExecutionErrorCategory `json:"category"`
+	Count        int64                       `json:"count"`
+	ExampleError string                      `json:"exampleError"`
+	Alerts       []*AlertListItemDTO         `json:"alerts"`
+}
+
+type AlertStateAtDTO struct {
+	AlertId      int64          `json:"alertId"`
+	State        AlertStateType `json:"state"`
+	NewStateDate time.Time      `json:"newStateDate"`
+}
+
+type AlertNotificationCountDTO struct {
+	AlertListItemDTO
+	NotificationCount int `json:"notificationCount"`
+}
+
+type UnmonitoredServiceDTO struct {
+	Service    string `json:"service"`
+	AlertCount int64  `json:"alertCount"`
+}
+
+type AlertTagCountDTO struct {
+	AlertListItemDTO `xorm:"extends"`
+	TagCount         int `json:"tagCount"`
+}
+
+type ForDurationBucket struct {
+	UpperBound time.Duration `json:"upperBound"`
+	AlertCount int64         `json:"alertCount"`
+}
+
+type AlertNameLengthDTO struct {
+	AlertListItemDTO `xorm:"extends"`
+	NameLength       int `json:"nameLength"`
+	// SuggestedName is the name truncated to AlertNameMaxLength, set for
+	// names that are longer
+	SuggestedName string `json:"suggestedName,omitempty" xorm:"-"`
+}
+
+type AlertDetailDTO struct {
+	Alert          *Alert                     `json:"alert"`
+	DashboardUid   string                     `json:"dashboardUid"`
+	DashboardTitle string                     `json:"dashboardTitle"`
+	Tags           []*Tag                     `json:"tags"`
+	Notifications  []*AlertNotificationRefDTO `json:"notifications"`
+}
+
+// AlertExportDTO references the dashboard and notification channels of an
+// alert by uid. The notifications in Settings are rewritten to uids as well,
+// references to channels that no longer exist are left out.
+type AlertExportDTO struct {
+	DashboardUid     string            `json:"dashboardUid"`
+	PanelId          int64             `json:"panelId"`
+	Name             string            `json:"name"`
+	Message          string            `json:"message"`
+	Frequency        int64             `json:"frequency"`
+	For              time.Duration     `json:"for"`
+	Tags             map[string]string `json:"tags"`
+	NotificationUids []string          `json:"notificationUids"`
+	Settings         *simplejson.Json  `json:"settings"`
+}
+
+type AlertValidationErrorDTO struct {
+	PanelId int64  `json:"panelId"`
+	Name    string `json:"name"`
+	Error   string `json:"error"`
+}
+
+type AlertNotificationRefDTO struct {
+	Id   int64  `json:"id"`
+	Uid  string `json:"uid"`
+	Name string `json:"name"`
+}
+
+type CapacityForecast struct {
+	Interval                time.Duration `json:"interval"`
+	EstimatedEvalsPerSecond float64       `json:"estimatedEvalsPerSecond"`
+}
+
+type AlertDanglingNotificationsDTO struct {
+	AlertListItemDTO
+	DanglingNotifications []*AlertNotificationRef `json:"danglingNotifications"`
+}
+
+type AlertChannelGroupDTO struct {
+	AlertListItemDTO
+	// ChannelUids are the uids of the channels of the group the alert sends to
+	ChannelUids []string `json:"channelUids"`
+}
+
+type AlertSharedPanelDTO struct {
+	PanelId int64               `json:"panelId"`
+	Alerts  []*AlertListItemDTO `json:"alerts"`
+}
+
+type OrgAlertStateCount struct {
+	OrgId int64 `json:"orgId"`
+	Count int64 `json:"count"`
+}
+
+type AlertNameDTO struct {
+	Id      int64  `json:"id"`
+	Name    string `json:"name"`
+	PanelId int64  `json:"panelId"`
+}
+
+type AlertStateHourBucket struct {
+	HourStart   time.Time `json:"hourStart"`
+	ChangeCount int64     `json:"changeCount"`
+}
+
+type AlertHourOfDayCount struct {
+	Hour  int   `json:"hour"`
+	Count int64 `json:"count"`
+}
+
+type AlertNamingViolationDTO struct {
+	AlertListItemDTO
+	ExpectedPrefix string `json:"expectedPrefix"`
+}
+
+type AlertEvalDataValueDTO struct {
+	AlertListItemDTO
+	MatchingValue float64 `json:"matchingValue"`
+}
+
+type AlertFiringDurationDTO struct {
+	AlertId        int64         `json:"alertId"`
+	AlertName      string        `json:"alertName"`
+	FiringDuration time.Duration `json:"firingDuration"`
+}
+
+type DuplicateAlertGroupDTO struct {
+	Name   string   `json:"name"`
+	Alerts []*Alert `json:"alerts"`
+}
+
+type OrgAlertsDriftDTO struct {
+	OnlyInA        []string         `json:"onlyInA"`
+	OnlyInB        []string         `json:"onlyInB"`
+	Differing      []*AlertDriftDTO `json:"differing"`
+	AmbiguousNames []string         `json:"ambiguousNames"`
+}
+
+type AlertDriftDTO struct {
+	Name     string `json:"name"`
+	AlertIdA int64  `json:"alertIdA"`
+	AlertIdB int64  `json:"alertIdB"`
+}
+
+type TagCountBucket struct {
+	TagCount   int   `json:"tagCount"`
+	AlertCount int64 `json:"alertCount"`
+}
+
 type AlertStateInfoDTO struct {
 	Id           int64          `json:"id"`
 	DashboardId  int64          `json:"dashboardId"`
@@ -247,3 +1315,14 @@ type ValidateDashboardAlertsCommand struct {
 	Dashboard *Dashboard
 	User      *SignedInUser
 }
+
+// ValidateDashboardAlertsPerPanelCommand validates all alerts of a dashboard
+// without saving them. Unlike ValidateDashboardAlertsCommand it does not fail
+// on the first invalid alert but lists every problem found in Result.
+type ValidateDashboardAlertsPerPanelCommand struct {
+	OrgId     int64
+	Dashboard *Dashboard
+	User      *SignedInUser
+
+	Result []*AlertValidationErrorDTO
+}
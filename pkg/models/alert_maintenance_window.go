@@ -0,0 +1,87 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrAlertMaintenanceWindowNotFound     = errors.New("Alert maintenance window not found")
+	ErrAlertMaintenanceWindowInvalidRange = errors.New("Alert maintenance window has to end after it starts")
+	ErrAlertMaintenanceWindowNoAlerts     = errors.New("Alert maintenance window matches no alerts")
+)
+
+type AlertMaintenanceWindowState string
+
+const (
+	AlertMaintenanceWindowScheduled AlertMaintenanceWindowState = "scheduled"
+	AlertMaintenanceWindowActive    AlertMaintenanceWindowState = "active"
+	AlertMaintenanceWindowEnded     AlertMaintenanceWindowState = "ended"
+	AlertMaintenanceWindowCancelled AlertMaintenanceWindowState = "cancelled"
+)
+
+// AlertMaintenanceWindow pauses a set of alerts between Starts and Ends.
+// The alerts are paused and resumed by ApplyAlertMaintenanceWindowsCommand.
+type AlertMaintenanceWindow struct {
+	Id        int64                       `json:"id"`
+	OrgId     int64                       `json:"orgId"`
+	Starts    time.Time                   `json:"starts"`
+	Ends      time.Time                   `json:"ends"`
+	State     AlertMaintenanceWindowState `json:"state"`
+	CreatedBy int64                       `json:"createdBy"`
+	Created   time.Time                   `json:"created"`
+	Updated   time.Time                   `json:"updated"`
+}
+
+// AlertMaintenanceWindowAlert links an alert to a maintenance window. Paused
+// is set when the window paused the alert, alerts that were already paused
+// when the window started are left paused when it ends.
+type AlertMaintenanceWindowAlert struct {
+	Id       int64
+	WindowId int64
+	AlertId  int64
+	Paused   bool
+}
+
+// AlertMaintenanceWindowTransition is a state change of a maintenance window
+// applied by ApplyAlertMaintenanceWindowsCommand. AlertCount is the number of
+// alerts paused or resumed by it.
+type AlertMaintenanceWindowTransition struct {
+	WindowId   int64                       `json:"windowId"`
+	OrgId      int64                       `json:"orgId"`
+	State      AlertMaintenanceWindowState `json:"state"`
+	AlertCount int64                       `json:"alertCount"`
+}
+
+// CreateAlertMaintenanceWindowCommand schedules a maintenance window for the
+// alerts with the given ids and the alerts carrying all of the given tags.
+// The alerts are resolved when the window is created, alerts added later are
+// not part of it.
+type CreateAlertMaintenanceWindowCommand struct {
+	OrgId    int64
+	UserId   int64
+	Starts   time.Time
+	Ends     time.Time
+	AlertIds []int64
+	Tags     []string
+
+	Result *AlertMaintenanceWindow
+}
+
+// CancelAlertMaintenanceWindowCommand cancels a maintenance window. The
+// alerts paused by an active window are resumed right away.
+type CancelAlertMaintenanceWindowCommand struct {
+	OrgId int64
+	Id    int64
+
+	Result *AlertMaintenanceWindowTransition
+}
+
+// ApplyAlertMaintenanceWindowsCommand pauses the alerts of the windows that
+// have started at Now and resumes the alerts of the windows that have ended,
+// across all orgs.
+type ApplyAlertMaintenanceWindowsCommand struct {
+	Now time.Time
+
+	Result []*AlertMaintenanceWindowTransition
+}
@@ -60,5 +60,25 @@ func TestAlertingModelTest(t *testing.T) {
 				So(ContainsTag(actualTags, tag), ShouldBeTrue)
 			}
 		})
+
+		Convey("Should categorize execution errors", func() {
+			So(CategorizeExecutionError(`Post "http://localhost:9090": context deadline exceeded`), ShouldEqual, ExecutionErrorCategoryTimeout)
+			So(CategorizeExecutionError("dial tcp 127.0.0.1:9090: connect: connection refused"), ShouldEqual, ExecutionErrorCategoryConnection)
+			So(CategorizeExecutionError("tsdb.HandleRequest() error invalid query"), ShouldEqual, ExecutionErrorCategoryOther)
+		})
+
+		Convey("Alert filter should match name, state and tags", func() {
+			json2, err := simplejson.NewJson([]byte(`{ "alertRuleTags": { "severity": "critical" } }`))
+			So(err, ShouldBeNil)
+			rule1.Settings = json2
+			rule1.State = AlertStateAlerting
+
+			So((&AlertFilter{}).Matches(rule1), ShouldBeTrue)
+			So((&AlertFilter{Query: "nam"}).Matches(rule1), ShouldBeTrue)
+			So((&AlertFilter{Query: "other"}).Matches(rule1), ShouldBeFalse)
+			So((&AlertFilter{States: []AlertStateType{AlertStateOK}}).Matches(rule1), ShouldBeFalse)
+			So((&AlertFilter{Tags: []*Tag{{Key: "severity", Value: "critical"}}}).Matches(rule1), ShouldBeTrue)
+			So((&AlertFilter{Tags: []*Tag{{Key: "severity", Value: "warning"}}}).Matches(rule1), ShouldBeFalse)
+		})
 	})
 }
@@ -8,6 +8,7 @@ import (
 func init() {
 	bus.AddHandler("alerting", updateDashboardAlerts)
 	bus.AddHandler("alerting", validateDashboardAlerts)
+	bus.AddHandler("alerting", validateDashboardAlertsPerPanel)
 }
 
 func validateDashboardAlerts(cmd *models.ValidateDashboardAlertsCommand) error {
@@ -16,6 +17,18 @@ func validateDashboardAlerts(cmd *models.ValidateDashboardAlertsCommand) error {
 	return extractor.ValidateAlerts()
 }
 
+func validateDashboardAlertsPerPanel(cmd *models.ValidateDashboardAlertsPerPanelCommand) error {
+	extractor := NewDashAlertExtractor(cmd.Dashboard, cmd.OrgId, cmd.User)
+
+	result, err := extractor.ValidateAlertsPerPanel()
+	if err != nil {
+		return err
+	}
+
+	cmd.Result = result
+	return nil
+}
+
 func updateDashboardAlerts(cmd *models.UpdateDashboardAlertsCommand) error {
 	saveAlerts := models.SaveAlertsCommand{
 		OrgId:       cmd.OrgId,
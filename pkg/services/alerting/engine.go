@@ -159,7 +159,12 @@ func (e *AlertEngine) processJob(attemptID int, attemptChan chan int, cancelChan
 		}
 	}()
 
-	alertCtx, cancelFn := context.WithTimeout(context.Background(), setting.AlertingEvaluationTimeout)
+	evalTimeout := setting.AlertingEvaluationTimeout
+	if job.Rule.EvalTimeout > 0 {
+		evalTimeout = job.Rule.EvalTimeout
+	}
+
+	alertCtx, cancelFn := context.WithTimeout(context.Background(), evalTimeout)
 	cancelChan <- cancelFn
 	span := opentracing.StartSpan("alert execution")
 	alertCtx = opentracing.ContextWithSpan(alertCtx, span)
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/grafana/grafana/pkg/bus"
@@ -74,7 +75,15 @@ func copyJSON(in json.Marshaler) (*simplejson.Json, error) {
 	return simplejson.NewJson(rawJSON)
 }
 
-func (e *DashAlertExtractor) getAlertFromPanels(jsonWithPanels *simplejson.Json, validateAlertFunc func(*models.Alert) bool) ([]*models.Alert, error) {
+// panelErrorHandler is called with the error of an invalid panel alert.
+// Returning an error stops the extraction, returning nil skips the panel.
+type panelErrorHandler func(panelID int64, jsonAlert *simplejson.Json, err error) error
+
+func failOnPanelError(panelID int64, jsonAlert *simplejson.Json, err error) error {
+	return err
+}
+
+func (e *DashAlertExtractor) getAlertFromPanels(jsonWithPanels *simplejson.Json, validateAlertFunc func(*models.Alert) bool, handleErr panelErrorHandler) ([]*models.Alert, error) {
 	alerts := make([]*models.Alert, 0)
 
 	for _, panelObj := range jsonWithPanels.Get("panels").MustArray() {
@@ -84,7 +93,7 @@ func (e *DashAlertExtractor) getAlertFromPanels(jsonWithPanels *simplejson.Json,
 		// check if the panel is collapsed
 		if collapsed && collapsedJSON.MustBool() {
 			// extract alerts from sub panels for collapsed panels
-			alertSlice, err := e.getAlertFromPanels(panel, validateAlertFunc)
+			alertSlice, err := e.getAlertFromPanels(panel, validateAlertFunc, handleErr)
 			if err != nil {
 				return nil, err
 			}
@@ -101,7 +110,10 @@ func (e *DashAlertExtractor) getAlertFromPanels(jsonWithPanels *simplejson.Json,
 
 		panelID, err := panel.Get("id").Int64()
 		if err != nil {
-			return nil, ValidationError{Reason: "A numeric panel id property is missing"}
+			if err := handleErr(0, jsonAlert, ValidationError{Reason: "A numeric panel id property is missing"}); err != nil {
+				return nil, err
+			}
+			continue
 		}
 
 		// backward compatibility check, can be removed later
@@ -110,97 +122,124 @@ func (e *DashAlertExtractor) getAlertFromPanels(jsonWithPanels *simplejson.Json,
 			continue
 		}
 
-		frequency, err := getTimeDurationStringToSeconds(jsonAlert.Get("frequency").MustString())
+		alert, err := e.getAlertFromPanel(panel, panelID, jsonAlert, validateAlertFunc)
 		if err != nil {
-			return nil, ValidationError{Reason: err.Error()}
+			if err := handleErr(panelID, jsonAlert, err); err != nil {
+				return nil, err
+			}
+			continue
 		}
 
-		rawFor := jsonAlert.Get("for").MustString()
-		var forValue time.Duration
-		if rawFor != "" {
-			forValue, err = time.ParseDuration(rawFor)
-			if err != nil {
-				return nil, ValidationError{Reason: "Could not parse for"}
-			}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, nil
+}
+
+// getAlertFromPanel extracts and validates the alert of a single panel.
+func (e *DashAlertExtractor) getAlertFromPanel(panel *simplejson.Json, panelID int64, jsonAlert *simplejson.Json, validateAlertFunc func(*models.Alert) bool) (*models.Alert, error) {
+	frequency, err := getTimeDurationStringToSeconds(jsonAlert.Get("frequency").MustString())
+	if err != nil {
+		return nil, ValidationError{Reason: err.Error()}
+	}
+
+	rawFor := jsonAlert.Get("for").MustString()
+	var forValue time.Duration
+	if rawFor != "" {
+		forValue, err = time.ParseDuration(rawFor)
+		if err != nil {
+			return nil, ValidationError{Reason: "Could not parse for"}
 		}
+	}
 
-		alert := &models.Alert{
-			DashboardId: e.Dash.Id,
-			OrgId:       e.OrgID,
-			PanelId:     panelID,
-			Id:          jsonAlert.Get("id").MustInt64(),
-			Name:        jsonAlert.Get("name").MustString(),
-			Handler:     jsonAlert.Get("handler").MustInt64(),
-			Message:     jsonAlert.Get("message").MustString(),
-			Frequency:   frequency,
-			For:         forValue,
+	rawEvalTimeout := jsonAlert.Get("evalTimeout").MustString()
+	var evalTimeout time.Duration
+	if rawEvalTimeout != "" {
+		evalTimeout, err = time.ParseDuration(rawEvalTimeout)
+		if err != nil || evalTimeout < 0 {
+			return nil, ValidationError{Reason: "Could not parse evalTimeout"}
 		}
+	}
 
-		for _, condition := range jsonAlert.Get("conditions").MustArray() {
-			jsonCondition := simplejson.NewFromAny(condition)
+	alert := &models.Alert{
+		DashboardId: e.Dash.Id,
+		OrgId:       e.OrgID,
+		PanelId:     panelID,
+		Id:          jsonAlert.Get("id").MustInt64(),
+		Name:        jsonAlert.Get("name").MustString(),
+		Handler:     jsonAlert.Get("handler").MustInt64(),
+		Message:     jsonAlert.Get("message").MustString(),
+		Frequency:   frequency,
+		For:         forValue,
+		EvalTimeout: evalTimeout,
+	}
 
-			jsonQuery := jsonCondition.Get("query")
-			queryRefID := jsonQuery.Get("params").MustArray()[0].(string)
-			panelQuery := findPanelQueryByRefID(panel, queryRefID)
+	if strings.TrimSpace(alert.Name) == "" {
+		return nil, ValidationError{Reason: fmt.Sprintf("Alert on PanelId: %v has no name", panelID)}
+	}
 
-			if panelQuery == nil {
-				reason := fmt.Sprintf("Alert on PanelId: %v refers to query(%s) that cannot be found", alert.PanelId, queryRefID)
-				return nil, ValidationError{Reason: reason}
-			}
+	for _, condition := range jsonAlert.Get("conditions").MustArray() {
+		jsonCondition := simplejson.NewFromAny(condition)
 
-			dsName := ""
-			if panelQuery.Get("datasource").MustString() != "" {
-				dsName = panelQuery.Get("datasource").MustString()
-			} else if panel.Get("datasource").MustString() != "" {
-				dsName = panel.Get("datasource").MustString()
-			}
+		jsonQuery := jsonCondition.Get("query")
+		queryRefID := jsonQuery.Get("params").MustArray()[0].(string)
+		panelQuery := findPanelQueryByRefID(panel, queryRefID)
 
-			datasource, err := e.lookupDatasourceID(dsName)
-			if err != nil {
-				e.log.Debug("Error looking up datasource", "error", err)
-				return nil, ValidationError{Reason: fmt.Sprintf("Data source used by alert rule not found, alertName=%v, datasource=%s", alert.Name, dsName)}
-			}
+		if panelQuery == nil {
+			reason := fmt.Sprintf("Alert on PanelId: %v refers to query(%s) that cannot be found", alert.PanelId, queryRefID)
+			return nil, ValidationError{Reason: reason}
+		}
 
-			dsFilterQuery := models.DatasourcesPermissionFilterQuery{
-				User:        e.User,
-				Datasources: []*models.DataSource{datasource},
-			}
+		dsName := ""
+		if panelQuery.Get("datasource").MustString() != "" {
+			dsName = panelQuery.Get("datasource").MustString()
+		} else if panel.Get("datasource").MustString() != "" {
+			dsName = panel.Get("datasource").MustString()
+		}
 
-			if err := bus.Dispatch(&dsFilterQuery); err != nil {
-				if err != bus.ErrHandlerNotFound {
-					return nil, err
-				}
-			} else {
-				if len(dsFilterQuery.Result) == 0 {
-					return nil, models.ErrDataSourceAccessDenied
-				}
-			}
+		datasource, err := e.lookupDatasourceID(dsName)
+		if err != nil {
+			e.log.Debug("Error looking up datasource", "error", err)
+			return nil, ValidationError{Reason: fmt.Sprintf("Data source used by alert rule not found, alertName=%v, datasource=%s", alert.Name, dsName)}
+		}
 
-			jsonQuery.SetPath([]string{"datasourceId"}, datasource.Id)
+		dsFilterQuery := models.DatasourcesPermissionFilterQuery{
+			User:        e.User,
+			Datasources: []*models.DataSource{datasource},
+		}
 
-			if interval, err := panel.Get("interval").String(); err == nil {
-				panelQuery.Set("interval", interval)
+		if err := bus.Dispatch(&dsFilterQuery); err != nil {
+			if err != bus.ErrHandlerNotFound {
+				return nil, err
+			}
+		} else {
+			if len(dsFilterQuery.Result) == 0 {
+				return nil, models.ErrDataSourceAccessDenied
 			}
-
-			jsonQuery.Set("model", panelQuery.Interface())
 		}
 
-		alert.Settings = jsonAlert
+		jsonQuery.SetPath([]string{"datasourceId"}, datasource.Id)
 
-		// validate
-		_, err = NewRuleFromDBAlert(alert)
-		if err != nil {
-			return nil, err
+		if interval, err := panel.Get("interval").String(); err == nil {
+			panelQuery.Set("interval", interval)
 		}
 
-		if !validateAlertFunc(alert) {
-			return nil, ValidationError{Reason: fmt.Sprintf("Panel id is not correct, alertName=%v, panelId=%v", alert.Name, alert.PanelId)}
-		}
+		jsonQuery.Set("model", panelQuery.Interface())
+	}
 
-		alerts = append(alerts, alert)
+	alert.Settings = jsonAlert
+
+	// validate
+	_, err = NewRuleFromDBAlert(alert)
+	if err != nil {
+		return nil, err
 	}
 
-	return alerts, nil
+	if !validateAlertFunc(alert) {
+		return nil, ValidationError{Reason: fmt.Sprintf("Panel id is not correct, alertName=%v, panelId=%v", alert.Name, alert.PanelId)}
+	}
+
+	return alert, nil
 }
 
 func validateAlertRule(alert *models.Alert) bool {
@@ -209,10 +248,10 @@ func validateAlertRule(alert *models.Alert) bool {
 
 // GetAlerts extracts alerts from the dashboard json and does full validation on the alert json data.
 func (e *DashAlertExtractor) GetAlerts() ([]*models.Alert, error) {
-	return e.extractAlerts(validateAlertRule)
+	return e.extractAlerts(validateAlertRule, failOnPanelError)
 }
 
-func (e *DashAlertExtractor) extractAlerts(validateFunc func(alert *models.Alert) bool) ([]*models.Alert, error) {
+func (e *DashAlertExtractor) extractAlerts(validateFunc func(alert *models.Alert) bool, handleErr panelErrorHandler) ([]*models.Alert, error) {
 	dashboardJSON, err := copyJSON(e.Dash.Data)
 	if err != nil {
 		return nil, err
@@ -226,7 +265,7 @@ func (e *DashAlertExtractor) extractAlerts(validateFunc func(alert *models.Alert
 	if len(rows) > 0 {
 		for _, rowObj := range rows {
 			row := simplejson.NewFromAny(rowObj)
-			a, err := e.getAlertFromPanels(row, validateFunc)
+			a, err := e.getAlertFromPanels(row, validateFunc, handleErr)
 			if err != nil {
 				return nil, err
 			}
@@ -234,7 +273,7 @@ func (e *DashAlertExtractor) extractAlerts(validateFunc func(alert *models.Alert
 			alerts = append(alerts, a...)
 		}
 	} else {
-		a, err := e.getAlertFromPanels(dashboardJSON, validateFunc)
+		a, err := e.getAlertFromPanels(dashboardJSON, validateFunc, handleErr)
 		if err != nil {
 			return nil, err
 		}
@@ -249,6 +288,64 @@ func (e *DashAlertExtractor) extractAlerts(validateFunc func(alert *models.Alert
 // ValidateAlerts validates alerts in the dashboard json but does not require a valid dashboard id
 // in the first validation pass.
 func (e *DashAlertExtractor) ValidateAlerts() error {
-	_, err := e.extractAlerts(func(alert *models.Alert) bool { return alert.OrgId != 0 && alert.PanelId != 0 })
+	_, err := e.extractAlerts(validateAlertBeforeSave, failOnPanelError)
 	return err
 }
+
+func validateAlertBeforeSave(alert *models.Alert) bool {
+	return alert.OrgId != 0 && alert.PanelId != 0
+}
+
+// ValidateAlertsPerPanel validates the alerts in the dashboard json like
+// ValidateAlerts but does not stop at the first invalid alert. It also checks
+// that the notification channels the alerts send to exist. The returned
+// error is only set when the dashboard json could not be read at all.
+func (e *DashAlertExtractor) ValidateAlertsPerPanel() ([]*models.AlertValidationErrorDTO, error) {
+	result := make([]*models.AlertValidationErrorDTO, 0)
+
+	collect := func(panelID int64, jsonAlert *simplejson.Json, err error) error {
+		result = append(result, &models.AlertValidationErrorDTO{
+			PanelId: panelID,
+			Name:    jsonAlert.Get("name").MustString(),
+			Error:   err.Error(),
+		})
+		return nil
+	}
+
+	alerts, err := e.extractAlerts(validateAlertBeforeSave, collect)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, alert := range alerts {
+		if err := e.validateNotifications(alert); err != nil {
+			_ = collect(alert.PanelId, alert.Settings, err)
+		}
+	}
+
+	return result, nil
+}
+
+// validateNotifications checks that the notification channels referenced by
+// the alert exist. Saving an alert does not require that, rules just skip
+// channels that cannot be found.
+func (e *DashAlertExtractor) validateNotifications(alert *models.Alert) error {
+	for _, ref := range alert.GetNotificationsFromSettings() {
+		if ref.Uid == "" {
+			if _, err := translateNotificationIDToUID(ref.Id, e.OrgID); err != nil {
+				return ValidationError{Reason: fmt.Sprintf("Notification channel with id %v not found", ref.Id), PanelID: alert.PanelId}
+			}
+			continue
+		}
+
+		query := &models.GetAlertNotificationsWithUidQuery{OrgId: e.OrgID, Uid: ref.Uid}
+		if err := bus.Dispatch(query); err != nil {
+			return err
+		}
+		if query.Result == nil {
+			return ValidationError{Reason: fmt.Sprintf("Notification channel with uid %v not found", ref.Uid), PanelID: alert.PanelId}
+		}
+	}
+
+	return nil
+}
@@ -77,6 +77,22 @@ func TestAlertRuleExtraction(t *testing.T) {
 			})
 		})
 
+		Convey("Parsing dashboard with a blank alert name", func() {
+			dashJSON, err := simplejson.NewJson(json)
+			So(err, ShouldBeNil)
+
+			row := simplejson.NewFromAny(dashJSON.Get("rows").MustArray()[0])
+			panel := simplejson.NewFromAny(row.Get("panels").MustArray()[0])
+			panel.Get("alert").Set("name", "  ")
+
+			dash := models.NewDashboardFromJson(dashJSON)
+			extractor := NewDashAlertExtractor(dash, 1, nil)
+
+			_, err = extractor.GetAlerts()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "alert validation error: Alert on PanelId: 3 has no name")
+		})
+
 		Convey("Parsing and validating dashboard containing graphite alerts", func() {
 			dashJSON, err := simplejson.NewJson(json)
 			So(err, ShouldBeNil)
@@ -122,6 +138,11 @@ func TestAlertRuleExtraction(t *testing.T) {
 					So(alerts[1].For, ShouldEqual, time.Duration(0))
 				})
 
+				Convey("should extract evalTimeout param", func() {
+					So(alerts[0].EvalTimeout, ShouldEqual, time.Second*10)
+					So(alerts[1].EvalTimeout, ShouldEqual, time.Duration(0))
+				})
+
 				Convey("should extract name and desc", func() {
 					So(alerts[0].Name, ShouldEqual, "name1")
 					So(alerts[0].Message, ShouldEqual, "desc1")
@@ -254,6 +275,34 @@ func TestAlertRuleExtraction(t *testing.T) {
 				})
 			})
 
+			Convey("Validate every alert of a dashboard with invalid alerts", func() {
+				dashJSON, err := simplejson.NewJson(json)
+				So(err, ShouldBeNil)
+
+				row := simplejson.NewFromAny(dashJSON.Get("rows").MustArray()[0])
+				panels := row.Get("panels").MustArray()
+				simplejson.NewFromAny(panels[0]).Get("alert").Set("name", "")
+				simplejson.NewFromAny(panels[1]).Get("alert").Set("notifications", []interface{}{
+					map[string]interface{}{"uid": "notifier1"},
+					map[string]interface{}{"uid": "missing"},
+				})
+
+				dash := models.NewDashboardFromJson(dashJSON)
+				extractor := NewDashAlertExtractor(dash, 1, nil)
+
+				result, err := extractor.ValidateAlertsPerPanel()
+				So(err, ShouldBeNil)
+
+				Convey("Should list the problems of every alert", func() {
+					So(len(result), ShouldEqual, 2)
+					So(result[0].PanelId, ShouldEqual, 3)
+					So(result[0].Error, ShouldEqual, "alert validation error: Alert on PanelId: 3 has no name")
+					So(result[1].PanelId, ShouldEqual, 4)
+					So(result[1].Name, ShouldEqual, "name2")
+					So(result[1].Error, ShouldContainSubstring, "Notification channel with uid missing not found")
+				})
+			})
+
 			Convey("Parse and validate dashboard without id and containing an alert", func() {
 				json, err := ioutil.ReadFile("./testdata/dash-without-id.json")
 				So(err, ShouldBeNil)
@@ -96,6 +96,13 @@ type notificationService struct {
 }
 
 func (n *notificationService) SendIfNeeded(evalCtx *EvalContext) error {
+	// silenced rules keep being evaluated and changing state, they only
+	// stop notifying
+	if evalCtx.Rule.Silenced {
+		n.log.Debug("Not sending notifications for silenced alert rule", "ruleId", evalCtx.Rule.ID)
+		return nil
+	}
+
 	notifierStates, err := n.getNeededNotifiers(evalCtx.Rule.OrgID, evalCtx.Rule.Notifications, evalCtx)
 	if err != nil {
 		n.log.Error("Failed to get alert notifiers", "error", err)
@@ -115,6 +115,19 @@ func TestNotificationService(t *testing.T) {
 		require.Equalf(t, 0, scenarioCtx.imageUploadCount, "expected image not to be uploaded, but it was")
 		require.Truef(t, evalCtx.Ctx.Value(notificationSent{}).(bool), "expected notification to be sent, but wasn't")
 	})
+
+	silencedRule := *testRule
+	silencedRule.Silenced = true
+	silencedEvalCtx := NewEvalContext(context.Background(), &silencedRule)
+
+	notificationServiceScenario(t, "Given silenced alert rule should not render image nor send notification", silencedEvalCtx, true, func(scenarioCtx *scenarioContext) {
+		err := scenarioCtx.notificationService.SendIfNeeded(silencedEvalCtx)
+		require.NoError(t, err)
+
+		require.Equalf(t, 0, scenarioCtx.renderCount, "expected render not to be called, but it was")
+		require.Equalf(t, 0, scenarioCtx.imageUploadCount, "expected image not to be uploaded, but it was")
+		require.Nilf(t, silencedEvalCtx.Ctx.Value(notificationSent{}), "expected notification not to be sent, but it was")
+	})
 }
 
 type scenarioContext struct {
@@ -31,12 +31,14 @@ type Rule struct {
 	Message             string
 	LastStateChange     time.Time
 	For                 time.Duration
+	EvalTimeout         time.Duration
 	NoDataState         models.NoDataOption
 	ExecutionErrorState models.ExecutionErrorOption
 	State               models.AlertStateType
 	Conditions          []Condition
 	Notifications       []string
 	AlertRuleTags       []*models.Tag
+	Silenced            bool
 
 	StateChanges int64
 }
@@ -124,9 +126,11 @@ func NewRuleFromDBAlert(ruleDef *models.Alert) (*Rule, error) {
 	model.State = ruleDef.State
 	model.LastStateChange = ruleDef.NewStateDate
 	model.For = ruleDef.For
+	model.EvalTimeout = ruleDef.EvalTimeout
 	model.NoDataState = models.NoDataOption(ruleDef.Settings.Get("noDataState").MustString("no_data"))
 	model.ExecutionErrorState = models.ExecutionErrorOption(ruleDef.Settings.Get("executionErrorState").MustString("alerting"))
 	model.StateChanges = ruleDef.StateChanges
+	model.Silenced = ruleDef.Silenced
 
 	model.Frequency = ruleDef.Frequency
 	// frequency cannot be zero since that would not execute the alert rule.
@@ -3,16 +3,27 @@ package sqlstore
 import (
 	"bytes"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 // timeNow makes it possible to test usage of time
 var timeNow = time.Now
 
+// defaultAlertDeleteSafetyCap is the maximum number of alerts bulk deletes
+// remove unless the command sets another cap.
+const defaultAlertDeleteSafetyCap = 500
+
+// defaultAlertPageSize is used by paginated alert queries without a limit.
+const defaultAlertPageSize = 1000
+
 func init() {
 	bus.AddHandler("sql", SaveAlerts)
 	bus.AddHandler("sql", HandleAlertsQuery)
@@ -22,6 +33,70 @@ func init() {
 	bus.AddHandler("sql", GetAlertStatesForDashboard)
 	bus.AddHandler("sql", PauseAlert)
 	bus.AddHandler("sql", PauseAllAlerts)
+	bus.AddHandler("sql", GetAlertFrequencyViolations)
+	bus.AddHandler("sql", GetAlertDependencyCounts)
+	bus.AddHandler("sql", GetAlertsByNotificationUIDCount)
+	bus.AddHandler("sql", GetAlertsByConditionQueryParams)
+	bus.AddHandler("sql", SetAlertFrequencyByTier)
+	bus.AddHandler("sql", DeleteAlertsByNamePrefix)
+	bus.AddHandler("sql", GetAlertsByEvalDataSize)
+	bus.AddHandler("sql", GetAlertsByNotificationType)
+	bus.AddHandler("sql", GetAlertsByOrgAndStateCursor)
+	bus.AddHandler("sql", GetAlertsByExecutionErrorType)
+	bus.AddHandler("sql", GetAlertStatesAt)
+	bus.AddHandler("sql", SilenceAlertsByFilter)
+	bus.AddHandler("sql", GetAlertsByForZero)
+	bus.AddHandler("sql", GetAlertsByDashboardPermission)
+	bus.AddHandler("sql", GetOldestFiringAlert)
+	bus.AddHandler("sql", GetAlertsByNotificationCount)
+	bus.AddHandler("sql", GetAlertsByCreatedAt)
+	bus.AddHandler("sql", GetUnmonitoredServices)
+	bus.AddHandler("sql", GetAlertsBeyondFrequencyCap)
+	bus.AddHandler("sql", GetAlertsByTagCount)
+	bus.AddHandler("sql", GetAlertsByForDurationDistribution)
+	bus.AddHandler("sql", GetAlertsByNameLength)
+	bus.AddHandler("sql", SetExecutionErrorState)
+	bus.AddHandler("sql", GetAlertsByStateChangeSince)
+	bus.AddHandler("sql", GetAlertDetail)
+	bus.AddHandler("sql", GetAlertsByMultipleDashboardUids)
+	bus.AddHandler("sql", GetAlertingCapacityForecast)
+	bus.AddHandler("sql", GetAlertsWithDanglingNotifications)
+	bus.AddHandler("sql", PurgeDanglingAlertNotifications)
+	bus.AddHandler("sql", GetAlertsByStateAndAge)
+	bus.AddHandler("sql", GetAlertsForChannelGroup)
+	bus.AddHandler("sql", ImportAlertTags)
+	bus.AddHandler("sql", GetAlertsByPanel)
+	bus.AddHandler("sql", GetAlertsWithSharedPanelId)
+	bus.AddHandler("sql", GetEvaluationSchedulePreview)
+	bus.AddHandler("sql", GetAlertsBySettingsKey)
+	bus.AddHandler("sql", GetOrgsWithAlertStateCountAboveThreshold)
+	bus.AddHandler("sql", GetAlertNamesForDashboard)
+	bus.AddHandler("sql", GetAlertsByNewStateDateHour)
+	bus.AddHandler("sql", GetFiringByHourOfDay)
+	bus.AddHandler("sql", GetAlertsByConditionOperator)
+	bus.AddHandler("sql", GetAlertStatesForPanels)
+	bus.AddHandler("sql", GetAlertNamingViolations)
+	bus.AddHandler("sql", GetAlertsByEvalDataValue)
+	bus.AddHandler("sql", GetAlertsByOrgFiringDuration)
+	bus.AddHandler("sql", RenameAlertTagValue)
+	bus.AddHandler("sql", GetAlertsBySettingsSize)
+	bus.AddHandler("sql", GetAlertsWithBlankName)
+	bus.AddHandler("sql", GetAlertsByOrgAndNotPaused)
+	bus.AddHandler("sql", GetDuplicateAlertsOnDashboard)
+	bus.AddHandler("sql", CompareOrgAlerts)
+	bus.AddHandler("sql", GetAlertsByTagCountDistribution)
+	bus.AddHandler("sql", ReplaceNotificationChannel)
+	bus.AddHandler("sql", GetAlertsByPauseReason)
+	bus.AddHandler("sql", GetAlertsWithFewEvaluationsInFor)
+	bus.AddHandler("sql", GetAlertsByDashboardAndState)
+	bus.AddHandler("sql", ExportAlertsByFilter)
+	bus.AddHandler("sql", GetAlertCountByDashboard)
+	bus.AddHandler("sql", GetOverduePendingAlerts)
+	bus.AddHandler("sql", GetAlertsByAnnotationCount)
+	bus.AddHandler("sql", GetAlertsCreatedRecently)
+	bus.AddHandler("sql", GetDatasourceAlertErrorRates)
+	bus.AddHandler("sql", GetAlertsModifiedRecently)
+	bus.AddHandler("sql", GetAlertsByEvalDataNull)
 }
 
 func GetAlertById(query *models.GetAlertByIdQuery) error {
@@ -68,13 +143,15 @@ func deleteAlertByIdInternal(alertId int64, reason string, sess *DBSession) erro
 		return err
 	}
 
+	if _, err := sess.Exec("DELETE FROM alert_maintenance_window_alert WHERE alert_id = ?", alertId); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-func HandleAlertsQuery(query *models.GetAlertsQuery) error {
-	builder := SqlBuilder{}
-
-	builder.Write(`SELECT
+// alertListItemColumns are the columns of models.AlertListItemDTO.
+const alertListItemColumns = `
 		alert.id,
 		alert.dashboard_id,
 		alert.panel_id,
@@ -85,16 +162,172 @@ func HandleAlertsQuery(query *models.GetAlertsQuery) error {
 		alert.eval_date,
 		alert.execution_error,
 		dashboard.uid as dashboard_uid,
-		dashboard.slug as dashboard_slug
+		dashboard.slug as dashboard_slug`
+
+const alertListItemFrom = `
 		FROM alert
-		INNER JOIN dashboard on dashboard.id = alert.dashboard_id `)
+		INNER JOIN dashboard on dashboard.id = alert.dashboard_id `
 
-	builder.Write(`WHERE alert.org_id = ?`, query.OrgId)
+// alertListItemSelect selects the columns of models.AlertListItemDTO. Queries
+// building on it are expected to continue with a WHERE clause.
+const alertListItemSelect = `SELECT` + alertListItemColumns + alertListItemFrom
+
+// findAlertListItems runs the query in builder and cleans up the result
+// the same way for every alert list query.
+func findAlertListItems(builder *SqlBuilder) ([]*models.AlertListItemDTO, error) {
+	alerts := make([]*models.AlertListItemDTO, 0)
+	if err := x.SQL(builder.GetSqlString(), builder.params...).Find(&alerts); err != nil {
+		return nil, err
+	}
+
+	for _, alert := range alerts {
+		cleanAlertListItem(alert)
+	}
+
+	return alerts, nil
+}
+
+func cleanAlertListItem(alert *models.AlertListItemDTO) {
+	if alert.ExecutionError == " " {
+		alert.ExecutionError = ""
+	}
+}
+
+func HandleAlertsQuery(query *models.GetAlertsQuery) error {
+	filter := SqlBuilder{}
+	filter.Write(`WHERE alert.org_id = ?`, query.OrgId)
+
+	writeAlertsQueryFilter(&filter, query)
+
+	if query.ReducerType != "" || query.EvaluatorType != "" {
+		sess := newSession()
+		defer sess.Close()
+
+		if err := writeAlertConditionTypeFilter(&filter, query.OrgId, query, sess); err != nil {
+			return err
+		}
+	}
+
+	if query.User.OrgRole != models.ROLE_ADMIN {
+		filter.writeDashboardPermissionFilter(query.User, models.PERMISSION_VIEW)
+	}
+
+	builder := SqlBuilder{}
+	builder.Write(alertListItemSelect)
+	builder.Write(filter.GetSqlString(), filter.params...)
+
+	orderBy, err := alertsQueryOrderBy(query)
+	if err != nil {
+		return err
+	}
+	builder.Write(orderBy)
+
+	var paging *models.AlertsPaging
+	if query.Page != nil {
+		paging = &models.AlertsPaging{Page: query.Page.PageNumber, PageSize: query.Page.PageSize}
+		if paging.Page < 1 {
+			paging.Page = 1
+		}
+		if paging.PageSize < 1 {
+			paging.PageSize = defaultAlertPageSize
+		}
+		builder.Write(dialect.LimitOffset(paging.PageSize, (paging.Page-1)*paging.PageSize))
+	} else if query.Offset > 0 {
+		limit := query.Limit
+		if limit <= 0 {
+			limit = defaultAlertPageSize
+		}
+		builder.Write(dialect.LimitOffset(limit, query.Offset))
+	} else if query.Limit != 0 {
+		builder.Write(dialect.Limit(query.Limit))
+	}
+
+	alerts, err := findAlertListItems(&builder)
+	if err != nil {
+		return err
+	}
+
+	if paging != nil {
+		counts := make([]*targetCount, 0)
+		rawSql := `SELECT COUNT(*) AS count` + alertListItemFrom + filter.GetSqlString()
+		if err := x.SQL(rawSql, filter.params...).Find(&counts); err != nil {
+			return err
+		}
+
+		paging.Total = counts[0].Count
+		paging.TotalPages = (paging.Total + paging.PageSize - 1) / paging.PageSize
+	}
+
+	if query.WithStateCounts {
+		type stateCount struct {
+			State models.AlertStateType
+			Count int64
+		}
+
+		counts := make([]*stateCount, 0)
+		rawSql := `SELECT alert.state, COUNT(*) AS count` + alertListItemFrom + filter.GetSqlString() + ` GROUP BY alert.state`
+		if err := x.SQL(rawSql, filter.params...).Find(&counts); err != nil {
+			return err
+		}
+
+		query.StateCounts = make(map[models.AlertStateType]int64, len(counts))
+		for _, c := range counts {
+			query.StateCounts[c.State] = c.Count
+		}
+	}
 
+	query.Result = alerts
+	query.Paging = paging
+	return nil
+}
+
+// alertSortColumns maps the SortBy values of GetAlertsQuery to the column
+// they sort by. Only these columns can be sorted by.
+var alertSortColumns = map[string]string{
+	"name":           "alert.name",
+	"state":          "alert.state",
+	"new_state_date": "alert.new_state_date",
+}
+
+func alertsQueryOrderBy(query *models.GetAlertsQuery) (string, error) {
+	direction := "ASC"
+	if query.SortDesc {
+		direction = "DESC"
+	}
+
+	sortBy := query.SortBy
+	if sortBy == "" {
+		sortBy = "name"
+	}
+
+	if sortBy == "dashboard" {
+		// alerts without a dashboard have dashboard_id 0 and sort first
+		return " ORDER BY alert.dashboard_id " + direction + ", alert.panel_id " + direction + ", alert.name ASC", nil
+	}
+
+	column, ok := alertSortColumns[sortBy]
+	if !ok {
+		return "", models.ErrInvalidAlertSortBy
+	}
+	if column == "alert.name" {
+		return " ORDER BY alert.name " + direction, nil
+	}
+
+	return " ORDER BY " + column + " " + direction + ", alert.name ASC", nil
+}
+
+// writeAlertsQueryFilter writes the filters of query, except for the
+// permission filter, to a builder that selects from alert joined with
+// dashboard and already has a WHERE clause.
+func writeAlertsQueryFilter(builder *SqlBuilder, query *models.GetAlertsQuery) {
 	if len(strings.TrimSpace(query.Query)) > 0 {
 		builder.Write(" AND alert.name "+dialect.LikeStr()+" ?", "%"+query.Query+"%")
 	}
 
+	if len(strings.TrimSpace(query.MessageQuery)) > 0 {
+		builder.Write(" AND alert.message "+dialect.LikeStr()+" ?", "%"+query.MessageQuery+"%")
+	}
+
 	if len(query.DashboardIDs) > 0 {
 		builder.sql.WriteString(` AND alert.dashboard_id IN (?` + strings.Repeat(",?", len(query.DashboardIDs)-1) + `) `)
 
@@ -107,6 +340,64 @@ func HandleAlertsQuery(query *models.GetAlertsQuery) error {
 		builder.Write(` AND alert.panel_id = ?`, query.PanelId)
 	}
 
+	if query.MaxFrequency != 0 {
+		builder.Write(` AND alert.frequency <= ?`, query.MaxFrequency)
+	}
+
+	if query.TeamId != 0 {
+		builder.writeTeamDashboardPermissionFilter(query.OrgId, query.TeamId, models.PERMISSION_EDIT)
+	}
+
+	if query.GeneralFolderOnly {
+		builder.Write(` AND dashboard.folder_id = 0`)
+	}
+
+	if query.DashboardSlugLike != "" {
+		parts := strings.Split(query.DashboardSlugLike, "*")
+		for i, part := range parts {
+			parts[i] = escapeLikePattern(part)
+		}
+		builder.Write(` AND dashboard.slug LIKE ?`+likeEscape, strings.Join(parts, "%"))
+	}
+
+	if query.NoSeries {
+		// the result handler stores {"noData":true} as eval data when no series were returned
+		builder.Write(` AND alert.eval_data LIKE ?`, `%"noData":true%`)
+	}
+
+	if len(query.Tags) > 0 {
+		keyValueFilters := []string{}
+		tagParams := []interface{}{}
+
+		tags := models.ParseTagPairs(query.Tags)
+		for _, tag := range tags {
+			if tag.Value == "" {
+				keyValueFilters = append(keyValueFilters, "(tag."+dialect.Quote("key")+" = ?)")
+				tagParams = append(tagParams, tag.Key)
+			} else {
+				keyValueFilters = append(keyValueFilters, "(tag."+dialect.Quote("key")+" = ? AND tag."+dialect.Quote("value")+" = ?)")
+				tagParams = append(tagParams, tag.Key, tag.Value)
+			}
+		}
+
+		if len(tags) > 0 {
+			tagsSubQuery := fmt.Sprintf(`
+				SELECT SUM(1) FROM alert_rule_tag art
+					INNER JOIN tag on tag.id = art.tag_id
+					WHERE art.alert_id = alert.id
+						AND (
+							%s
+						)
+			`, strings.Join(keyValueFilters, " OR "))
+
+			if query.TagsMatchAny {
+				builder.Write(fmt.Sprintf(" AND (%s) > 0 ", tagsSubQuery), tagParams...)
+			} else {
+				builder.Write(fmt.Sprintf(" AND (%s) = %d ", tagsSubQuery, len(tags)), tagParams...)
+			}
+		}
+	}
+
 	if len(query.State) > 0 && query.State[0] != "all" {
 		builder.Write(` AND (`)
 		for i, v := range query.State {
@@ -123,179 +414,2547 @@ func HandleAlertsQuery(query *models.GetAlertsQuery) error {
 		}
 		builder.Write(")")
 	}
+}
 
-	if query.User.OrgRole != models.ROLE_ADMIN {
-		builder.writeDashboardPermissionFilter(query.User, models.PERMISSION_VIEW)
-	}
-
-	builder.Write(" ORDER BY name ASC")
-
-	if query.Limit != 0 {
-		builder.Write(dialect.Limit(query.Limit))
+// writeAlertConditionTypeFilter writes the ReducerType and EvaluatorType
+// filters of query. Condition types only exist in the alert settings, so
+// the alerts of the org are decoded to find the matching ids first.
+func writeAlertConditionTypeFilter(builder *SqlBuilder, orgId int64, query *models.GetAlertsQuery, sess *DBSession) error {
+	if query.ReducerType == "" && query.EvaluatorType == "" {
+		return nil
 	}
 
-	alerts := make([]*models.AlertListItemDTO, 0)
-	if err := x.SQL(builder.GetSqlString(), builder.params...).Find(&alerts); err != nil {
+	alerts, err := getAlertsByOrgId(orgId, sess)
+	if err != nil {
 		return err
 	}
 
-	for i := range alerts {
-		if alerts[i].ExecutionError == " " {
-			alerts[i].ExecutionError = ""
+	ids := make([]interface{}, 0)
+	for _, alert := range alerts {
+		if alertHasConditionType(alert, query.ReducerType, query.EvaluatorType) {
+			ids = append(ids, alert.Id)
 		}
 	}
 
-	query.Result = alerts
+	if len(ids) == 0 {
+		// no alert matches, IN () is not valid SQL
+		builder.Write(` AND 1 = 0`)
+		return nil
+	}
+
+	builder.Write(` AND alert.id IN (?`+strings.Repeat(",?", len(ids)-1)+`)`, ids...)
 	return nil
 }
 
-func deleteAlertDefinition(dashboardId int64, sess *DBSession) error {
-	alerts := make([]*models.Alert, 0)
-	if err := sess.Where("dashboard_id = ?", dashboardId).Find(&alerts); err != nil {
-		return err
+// alertHasConditionType reports whether one of the conditions of an alert
+// uses both the given reducer and evaluator type. An empty type matches any.
+func alertHasConditionType(alert *models.Alert, reducerType string, evaluatorType string) bool {
+	if alert.Settings == nil {
+		return false
 	}
 
-	for _, alert := range alerts {
-		if err := deleteAlertByIdInternal(alert.Id, "Dashboard deleted", sess); err != nil {
-			// If we return an error, the current transaction gets rolled back, so no use
-			// trying to delete more
-			return err
+	for _, condition := range alert.Settings.Get("conditions").MustArray() {
+		jsonCondition := simplejson.NewFromAny(condition)
+		if reducerType != "" && jsonCondition.GetPath("reducer", "type").MustString() != reducerType {
+			continue
 		}
+		if evaluatorType != "" && jsonCondition.GetPath("evaluator", "type").MustString() != evaluatorType {
+			continue
+		}
+		return true
 	}
 
-	return nil
+	return false
 }
 
-func SaveAlerts(cmd *models.SaveAlertsCommand) error {
-	return inTransaction(func(sess *DBSession) error {
-		existingAlerts, err := GetAlertsByDashboardId2(cmd.DashboardId, sess)
-		if err != nil {
-			return err
-		}
+// getAlertIdsByFilter returns the ids of the alerts of an org matching the
+// filters of a GetAlertsQuery. The permission filter is applied only when
+// the filter has a user. A nil filter matches every alert of the org.
+func getAlertIdsByFilter(orgId int64, filter *models.GetAlertsQuery, sess *DBSession) ([]int64, error) {
+	builder := SqlBuilder{}
+	builder.Write(`SELECT alert.id` + alertListItemFrom)
+	builder.Write(`WHERE alert.org_id = ?`, orgId)
 
-		if err := updateAlerts(existingAlerts, cmd, sess); err != nil {
-			return err
-		}
+	if filter != nil {
+		writeAlertsQueryFilter(&builder, filter)
 
-		if err := deleteMissingAlerts(existingAlerts, cmd, sess); err != nil {
-			return err
+		if err := writeAlertConditionTypeFilter(&builder, orgId, filter, sess); err != nil {
+			return nil, err
 		}
 
-		return nil
-	})
-}
+		if filter.User != nil {
+			builder.writeDashboardPermissionFilter(filter.User, models.PERMISSION_VIEW)
+		}
+	}
 
-func updateAlerts(existingAlerts []*models.Alert, cmd *models.SaveAlertsCommand, sess *DBSession) error {
-	for _, alert := range cmd.Alerts {
-		update := false
-		var alertToUpdate *models.Alert
+	builder.Write(` ORDER BY alert.id ASC`)
 
-		for _, k := range existingAlerts {
-			if alert.PanelId == k.PanelId {
-				update = true
-				alert.Id = k.Id
-				alertToUpdate = k
-				break
-			}
-		}
+	alerts := []struct {
+		Id int64
+	}{}
+	if err := sess.SQL(builder.GetSqlString(), builder.params...).Find(&alerts); err != nil {
+		return nil, err
+	}
 
-		if update {
-			if alertToUpdate.ContainsUpdates(alert) {
-				alert.Updated = timeNow()
-				alert.State = alertToUpdate.State
-				sess.MustCols("message", "for")
+	ids := make([]int64, 0, len(alerts))
+	for _, alert := range alerts {
+		ids = append(ids, alert.Id)
+	}
 
-				_, err := sess.ID(alert.Id).Update(alert)
-				if err != nil {
-					return err
-				}
+	return ids, nil
+}
 
-				sqlog.Debug("Alert updated", "name", alert.Name, "id", alert.Id)
-			}
-		} else {
-			alert.Updated = timeNow()
-			alert.Created = timeNow()
-			alert.State = models.AlertStateUnknown
-			alert.NewStateDate = timeNow()
+func GetAlertFrequencyViolations(query *models.GetAlertFrequencyViolationsQuery) error {
+	builder := SqlBuilder{}
 
-			_, err := sess.Insert(alert)
-			if err != nil {
-				return err
-			}
+	builder.Write(alertListItemSelect)
+	builder.Write(`WHERE alert.org_id = ? AND alert.frequency < ?`, query.OrgId, query.MaxAllowedFrequency)
+	builder.Write(" ORDER BY name ASC")
 
-			sqlog.Debug("Alert inserted", "name", alert.Name, "id", alert.Id)
-		}
-		tags := alert.GetTagsFromSettings()
-		if _, err := sess.Exec("DELETE FROM alert_rule_tag WHERE alert_id = ?", alert.Id); err != nil {
-			return err
-		}
-		if tags != nil {
-			tags, err := EnsureTagsExist(sess, tags)
-			if err != nil {
-				return err
-			}
-			for _, tag := range tags {
-				if _, err := sess.Exec("INSERT INTO alert_rule_tag (alert_id, tag_id) VALUES(?,?)", alert.Id, tag.Id); err != nil {
-					return err
-				}
-			}
-		}
+	alerts, err := findAlertListItems(&builder)
+	if err != nil {
+		return err
 	}
 
+	query.Result = alerts
 	return nil
 }
 
-func deleteMissingAlerts(alerts []*models.Alert, cmd *models.SaveAlertsCommand, sess *DBSession) error {
-	for _, missingAlert := range alerts {
-		missing := true
+// GetAlertsBeyondFrequencyCap is like GetAlertFrequencyViolations but leaves
+// out alerts without a frequency, which are evaluated every 60 seconds.
+func GetAlertsBeyondFrequencyCap(query *models.GetAlertsBeyondFrequencyCapQuery) error {
+	builder := SqlBuilder{}
 
-		for _, k := range cmd.Alerts {
-			if missingAlert.PanelId == k.PanelId {
-				missing = false
-				break
-			}
-		}
+	builder.Write(alertListItemSelect)
+	builder.Write(`WHERE alert.org_id = ? AND alert.frequency < ? AND alert.frequency > 0`, query.OrgId, query.FrequencyCap)
+	builder.Write(" ORDER BY name ASC")
 
-		if missing {
-			if err := deleteAlertByIdInternal(missingAlert.Id, "Removed from dashboard", sess); err != nil {
-				// No use trying to delete more, since we're in a transaction and it will be
-				// rolled back on error.
-				return err
-			}
-		}
+	alerts, err := findAlertListItems(&builder)
+	if err != nil {
+		return err
 	}
 
+	query.Result = alerts
 	return nil
 }
 
-func GetAlertsByDashboardId2(dashboardId int64, sess *DBSession) ([]*models.Alert, error) {
-	alerts := make([]*models.Alert, 0)
-	err := sess.Where("dashboard_id = ?", dashboardId).Find(&alerts)
+// GetAlertDependencyCounts counts the rows in the tables that
+// deleteAlertByIdInternal cleans up for each of the given alerts.
+func GetAlertDependencyCounts(query *models.GetAlertDependencyCountsQuery) error {
+	query.Result = make([]*models.AlertDependencyCountsDTO, 0)
+	if len(query.AlertIds) == 0 {
+		return nil
+	}
 
-	if err != nil {
-		return []*models.Alert{}, err
+	alertIds := []struct {
+		Id int64
+	}{}
+
+	builder := SqlBuilder{}
+	builder.Write(`SELECT id FROM alert WHERE org_id = ? AND id IN (?`+strings.Repeat(",?", len(query.AlertIds)-1)+`) ORDER BY id ASC`, query.OrgId)
+	for _, id := range query.AlertIds {
+		builder.AddParams(id)
 	}
 
-	return alerts, nil
-}
+	if err := x.SQL(builder.GetSqlString(), builder.params...).Find(&alertIds); err != nil {
+		return err
+	}
 
-func SetAlertState(cmd *models.SetAlertStateCommand) error {
-	return inTransaction(func(sess *DBSession) error {
-		alert := models.Alert{}
+	if len(alertIds) == 0 {
+		return nil
+	}
 
-		if has, err := sess.ID(cmd.AlertId).Get(&alert); err != nil {
-			return err
-		} else if !has {
-			return fmt.Errorf("Could not find alert")
-		}
+	ids := make([]int64, 0, len(alertIds))
+	for _, a := range alertIds {
+		ids = append(ids, a.Id)
+	}
 
-		if alert.State == models.AlertStatePaused {
-			return models.ErrCannotChangeStateOnPausedAlert
-		}
+	annotations, err := countRowsByAlertId("annotation", ids)
+	if err != nil {
+		return err
+	}
 
-		if alert.State == cmd.State {
-			return models.ErrRequiresNewState
-		}
+	tags, err := countRowsByAlertId("alert_rule_tag", ids)
+	if err != nil {
+		return err
+	}
+
+	notificationStates, err := countRowsByAlertId("alert_notification_state", ids)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		query.Result = append(query.Result, &models.AlertDependencyCountsDTO{
+			AlertId:            id,
+			Annotations:        annotations[id],
+			AlertRuleTags:      tags[id],
+			NotificationStates: notificationStates[id],
+		})
+	}
+
+	return nil
+}
+
+// countRowsByAlertId counts the rows of table per alert_id for the given alerts.
+func countRowsByAlertId(table string, alertIds []int64) (map[int64]int64, error) {
+	builder := SqlBuilder{}
+	builder.Write(`SELECT alert_id, COUNT(*) AS count FROM ` + table + ` WHERE alert_id IN (?` + strings.Repeat(",?", len(alertIds)-1) + `) GROUP BY alert_id`)
+	for _, id := range alertIds {
+		builder.AddParams(id)
+	}
+
+	type alertRowCount struct {
+		AlertId int64
+		Count   int64
+	}
+
+	rows := make([]*alertRowCount, 0)
+	if err := x.SQL(builder.GetSqlString(), builder.params...).Find(&rows); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[int64]int64, len(rows))
+	for _, row := range rows {
+		counts[row.AlertId] = row.Count
+	}
+
+	return counts, nil
+}
+
+func GetAlertsByNotificationUIDCount(query *models.GetAlertsByNotificationUIDCountQuery) error {
+	return inTransaction(func(sess *DBSession) error {
+		notificationQuery := &models.GetAlertNotificationsWithUidQuery{OrgId: query.OrgId, Uid: query.NotificationUID}
+		if err := getAlertNotificationWithUidInternal(notificationQuery, sess); err != nil {
+			return err
+		}
+
+		if notificationQuery.Result == nil {
+			return models.ErrAlertNotificationNotFound
+		}
+
+		alerts, err := getAlertsReferencingNotification(notificationQuery.Result, sess)
+		if err != nil {
+			return err
+		}
+
+		query.AlertIds = make([]int64, 0, len(alerts))
+		for _, alert := range alerts {
+			query.AlertIds = append(query.AlertIds, alert.Id)
+		}
+		query.AlertCount = int64(len(query.AlertIds))
+
+		return nil
+	})
+}
+
+func GetAlertsByNotificationType(query *models.GetAlertsByNotificationTypeQuery) error {
+	notifications := make([]*models.AlertNotification, 0)
+	if err := x.Where("org_id = ? AND type = ?", query.OrgId, query.Type).Find(&notifications); err != nil {
+		return err
+	}
+
+	if len(notifications) == 0 {
+		query.Result = make([]*models.AlertListItemDTO, 0)
+		return nil
+	}
+
+	sess := newSession()
+	defer sess.Close()
+
+	alerts, err := getAlertsByOrgId(query.OrgId, sess)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int64, 0)
+	for _, alert := range alerts {
+		if alertReferencesAnyNotification(alert, notifications) {
+			ids = append(ids, alert.Id)
+		}
+	}
+
+	query.Result, err = getAlertListItemsByIds(query.OrgId, ids)
+	return err
+}
+
+func alertReferencesAnyNotification(alert *models.Alert, notifications []*models.AlertNotification) bool {
+	for _, ref := range alert.GetNotificationsFromSettings() {
+		for _, notification := range notifications {
+			if ref.Matches(notification) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// getAlertsReferencingNotification returns the alerts in the notification's
+// org whose settings reference the notification channel by id or uid.
+func getAlertsReferencingNotification(notification *models.AlertNotification, sess *DBSession) ([]*models.Alert, error) {
+	alerts, err := getAlertsByOrgId(notification.OrgId, sess)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*models.Alert, 0)
+	for _, alert := range alerts {
+		if alertReferencesAnyNotification(alert, []*models.AlertNotification{notification}) {
+			result = append(result, alert)
+		}
+	}
+
+	return result, nil
+}
+
+func GetAlertsByConditionQueryParams(query *models.GetAlertsByConditionQueryParamsQuery) error {
+	sess := newSession()
+	defer sess.Close()
+
+	alerts, err := getAlertsByOrgId(query.OrgId, sess)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int64, 0)
+	for _, alert := range alerts {
+		if alertHasConditionQueryParam(alert, query.ParamIndex, query.ParamValue) {
+			ids = append(ids, alert.Id)
+		}
+	}
+
+	query.Result, err = getAlertListItemsByIds(query.OrgId, ids)
+	return err
+}
+
+func alertHasConditionQueryParam(alert *models.Alert, index int, value string) bool {
+	if alert.Settings == nil || index < 0 {
+		return false
+	}
+
+	for _, condition := range alert.Settings.Get("conditions").MustArray() {
+		params := simplejson.NewFromAny(condition).GetPath("query", "params").MustStringArray()
+		if index < len(params) && params[index] == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// byteLengthExpr returns an expression for the size in bytes of a text
+// column, which LENGTH counts in characters on Postgres and SQLite.
+func byteLengthExpr(column string) string {
+	switch dialect.DriverName() {
+	case migrator.POSTGRES:
+		return "OCTET_LENGTH(" + column + "::text)"
+	case migrator.SQLITE:
+		return "LENGTH(CAST(" + column + " AS BLOB))"
+	default:
+		return "LENGTH(" + column + ")"
+	}
+}
+
+func GetAlertsByEvalDataSize(query *models.GetAlertsByEvalDataSizeQuery) error {
+	sizeExpr := byteLengthExpr("alert.eval_data")
+
+	builder := SqlBuilder{}
+	builder.Write(`SELECT` + alertListItemColumns + `, ` + sizeExpr + ` AS eval_data_bytes` + alertListItemFrom)
+	builder.Write(`WHERE alert.org_id = ? AND `+sizeExpr+` >= ?`, query.OrgId, query.MinBytes)
+	builder.Write(` ORDER BY eval_data_bytes DESC`)
+
+	alerts := make([]*models.AlertEvalDataSizeDTO, 0)
+	if err := x.SQL(builder.GetSqlString(), builder.params...).Find(&alerts); err != nil {
+		return err
+	}
+
+	for _, alert := range alerts {
+		cleanAlertListItem(&alert.AlertListItemDTO)
+	}
+
+	query.Result = alerts
+	return nil
+}
+
+func GetAlertsByPauseReason(query *models.GetAlertsByPauseReasonQuery) error {
+	builder := SqlBuilder{}
+	builder.Write(`SELECT` + alertListItemColumns + `, alert.pause_reason, alert.paused_by` + alertListItemFrom)
+	builder.Write(`WHERE alert.org_id = ? AND alert.state = ?`, query.OrgId, models.AlertStatePaused)
+	// alerts paused before pause_reason existed have NULL, which LIKE '%%'
+	// would not match
+	if query.PauseReason != "" {
+		builder.Write(" AND alert.pause_reason "+dialect.LikeStr()+" ?", "%"+query.PauseReason+"%")
+	}
+	builder.Write(` ORDER BY alert.name ASC`)
+
+	alerts := make([]*models.AlertPauseInfoDTO, 0)
+	if err := x.SQL(builder.GetSqlString(), builder.params...).Find(&alerts); err != nil {
+		return err
+	}
+
+	for _, alert := range alerts {
+		cleanAlertListItem(&alert.AlertListItemDTO)
+	}
+
+	query.Result = alerts
+	return nil
+}
+
+func GetAlertsBySettingsSize(query *models.GetAlertsBySettingsSizeQuery) error {
+	sizeExpr := byteLengthExpr("alert.settings")
+
+	builder := SqlBuilder{}
+	builder.Write(`SELECT` + alertListItemColumns + `, ` + sizeExpr + ` AS settings_size_bytes` + alertListItemFrom)
+	builder.Write(`WHERE alert.org_id = ? AND `+sizeExpr+` >= ?`, query.OrgId, query.MinBytes)
+	builder.Write(` ORDER BY settings_size_bytes DESC`)
+
+	alerts := make([]*models.AlertSettingsSizeDTO, 0)
+	if err := x.SQL(builder.GetSqlString(), builder.params...).Find(&alerts); err != nil {
+		return err
+	}
+
+	for _, alert := range alerts {
+		cleanAlertListItem(&alert.AlertListItemDTO)
+	}
+
+	query.Result = alerts
+	return nil
+}
+
+// GetAlertsByOrgAndStateCursor pages through the alerts of an org ordered by
+// id, seeking past the cursor instead of using an offset.
+func GetAlertsByOrgAndStateCursor(query *models.GetAlertsByOrgAndStateCursorQuery) error {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultAlertPageSize
+	}
+
+	builder := SqlBuilder{}
+	builder.Write(alertListItemSelect)
+	builder.Write(`WHERE alert.org_id = ? AND alert.id > ?`, query.OrgId, query.AfterAlertId)
+
+	if len(query.States) > 0 {
+		builder.Write(` AND alert.state IN (?` + strings.Repeat(",?", len(query.States)-1) + `)`)
+		for _, state := range query.States {
+			builder.AddParams(state)
+		}
+	}
+
+	// fetch one extra row to know whether there is a next page
+	builder.Write(` ORDER BY alert.id ASC`)
+	builder.Write(dialect.Limit(int64(limit + 1)))
+
+	alerts, err := findAlertListItems(&builder)
+	if err != nil {
+		return err
+	}
+
+	query.NextCursor = 0
+	if len(alerts) > limit {
+		alerts = alerts[:limit]
+		query.NextCursor = alerts[limit-1].Id
+	}
+
+	query.Result = alerts
+	return nil
+}
+
+// GetAlertsByOrgAndNotPaused loads the next chunk of non-paused alerts of an
+// org after the cursor, like GetAlertsByOrgAndStateCursor but returning
+// full alerts for the scheduler.
+func GetAlertsByOrgAndNotPaused(query *models.GetAlertsByOrgAndNotPausedQuery) error {
+	limit := query.MaxAlertsPerFetch
+	if limit <= 0 {
+		limit = defaultAlertPageSize
+	}
+
+	alerts := make([]*models.Alert, 0)
+	// fetch one extra row to know whether there is a next chunk
+	err := x.Where("org_id = ? AND state <> ? AND id > ?", query.OrgId, models.AlertStatePaused, query.AfterAlertId).
+		Asc("id").
+		Limit(int(limit + 1)).
+		Find(&alerts)
+	if err != nil {
+		return err
+	}
+
+	query.NextCursor = 0
+	if int64(len(alerts)) > limit {
+		alerts = alerts[:limit]
+		query.NextCursor = alerts[limit-1].Id
+	}
+
+	query.Result = alerts
+	return nil
+}
+
+func GetAlertsModifiedRecently(query *models.GetAlertsModifiedRecentlyQuery) error {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultAlertPageSize
+	}
+
+	alerts := make([]*models.Alert, 0)
+	err := x.Where("org_id = ? AND updated >= ?", query.OrgId, timeNow().Add(-query.Since)).
+		Desc("updated").
+		Desc("id").
+		Limit(int(limit)).
+		Find(&alerts)
+	if err != nil {
+		return err
+	}
+
+	query.OldestUpdatedAt = time.Time{}
+	if len(alerts) > 0 {
+		query.OldestUpdatedAt = alerts[len(alerts)-1].Updated
+	}
+
+	query.Result = alerts
+	return nil
+}
+
+func GetAlertsByExecutionErrorType(query *models.GetAlertsByExecutionErrorTypeQuery) error {
+	builder := SqlBuilder{}
+	builder.Write(alertListItemSelect)
+	// SetAlertState stores a single space when there is no error
+	builder.Write(`WHERE alert.org_id = ? AND alert.execution_error <> '' AND alert.execution_error <> ' '`, query.OrgId)
+
+	if len(strings.TrimSpace(query.ErrorPattern)) > 0 {
+		builder.Write(" AND alert.execution_error "+dialect.LikeStr()+" ?", "%"+query.ErrorPattern+"%")
+	}
+
+	builder.Write(" ORDER BY name ASC")
+
+	alerts, err := findAlertListItems(&builder)
+	if err != nil {
+		return err
+	}
+
+	groups := make(map[models.AlertExecutionErrorCategory]*models.AlertExecutionErrorGroupDTO)
+	query.Result = make([]*models.AlertExecutionErrorGroupDTO, 0)
+	for _, alert := range alerts {
+		category := models.CategorizeExecutionError(alert.ExecutionError)
+		group, ok := groups[category]
+		if !ok {
+			group = &models.AlertExecutionErrorGroupDTO{
+				Category:     category,
+				ExampleError: alert.ExecutionError,
+				Alerts:       make([]*models.AlertListItemDTO, 0),
+			}
+			groups[category] = group
+			query.Result = append(query.Result, group)
+		}
+
+		group.Count++
+		group.Alerts = append(group.Alerts, alert)
+	}
+
+	sort.SliceStable(query.Result, func(i, j int) bool {
+		return query.Result[i].Count > query.Result[j].Count
+	})
+
+	return nil
+}
+
+// GetAlertStatesAt uses the annotations written on every alert state change
+// as state history and picks the latest one before the given time per alert.
+func GetAlertStatesAt(query *models.GetAlertStatesAtQuery) error {
+	epoch := query.At.UnixNano() / int64(time.Millisecond)
+
+	rawSql := `SELECT
+		annotation.id,
+		annotation.alert_id,
+		annotation.new_state,
+		annotation.epoch
+		FROM annotation
+		INNER JOIN (
+			SELECT alert_id, MAX(epoch) AS epoch FROM annotation
+			WHERE org_id = ? AND alert_id > 0 AND epoch <= ?
+			GROUP BY alert_id
+		) latest ON latest.alert_id = annotation.alert_id AND latest.epoch = annotation.epoch
+		WHERE annotation.org_id = ?
+		ORDER BY annotation.alert_id ASC, annotation.id ASC`
+
+	type stateChange struct {
+		Id       int64
+		AlertId  int64
+		NewState string
+		Epoch    int64
+	}
+
+	changes := make([]*stateChange, 0)
+	if err := x.SQL(rawSql, query.OrgId, epoch, query.OrgId).Find(&changes); err != nil {
+		return err
+	}
+
+	query.Result = make([]*models.AlertStateAtDTO, 0)
+	for _, change := range changes {
+		state := &models.AlertStateAtDTO{
+			AlertId:      change.AlertId,
+			State:        models.AlertStateType(change.NewState),
+			NewStateDate: time.Unix(0, change.Epoch*int64(time.Millisecond)),
+		}
+
+		// several changes in the same millisecond, the last one inserted wins
+		if n := len(query.Result); n > 0 && query.Result[n-1].AlertId == change.AlertId {
+			query.Result[n-1] = state
+			continue
+		}
+		query.Result = append(query.Result, state)
+	}
+
+	return nil
+}
+
+func GetAlertsByNotificationCount(query *models.GetAlertsByNotificationCountQuery) error {
+	sess := newSession()
+	defer sess.Close()
+
+	alerts, err := getAlertsByOrgId(query.OrgId, sess)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int64, 0)
+	counts := make(map[int64]int)
+	for _, alert := range alerts {
+		count := len(alert.GetNotificationsFromSettings())
+		if count >= query.MinNotifications && count <= query.MaxNotifications {
+			ids = append(ids, alert.Id)
+			counts[alert.Id] = count
+		}
+	}
+
+	items, err := getAlertListItemsByIds(query.OrgId, ids)
+	if err != nil {
+		return err
+	}
+
+	query.Result = make([]*models.AlertNotificationCountDTO, 0, len(items))
+	for _, item := range items {
+		query.Result = append(query.Result, &models.AlertNotificationCountDTO{
+			AlertListItemDTO:  *item,
+			NotificationCount: counts[item.Id],
+		})
+	}
+
+	return nil
+}
+
+func GetAlertsByTagCount(query *models.GetAlertsByTagCountQuery) error {
+	tagCount := `(SELECT COUNT(*) FROM alert_rule_tag WHERE alert_rule_tag.alert_id = alert.id)`
+
+	builder := SqlBuilder{}
+	builder.Write(`SELECT` + alertListItemColumns + `, ` + tagCount + ` AS tag_count` + alertListItemFrom)
+	builder.Write(`WHERE alert.org_id = ? AND `+tagCount+` BETWEEN ? AND ?`, query.OrgId, query.MinTags, query.MaxTags)
+	builder.Write(` ORDER BY name ASC`)
+
+	alerts := make([]*models.AlertTagCountDTO, 0)
+	if err := x.SQL(builder.GetSqlString(), builder.params...).Find(&alerts); err != nil {
+		return err
+	}
+
+	for _, alert := range alerts {
+		cleanAlertListItem(&alert.AlertListItemDTO)
+	}
+
+	query.Result = alerts
+	return nil
+}
+
+func GetAlertsByNameLength(query *models.GetAlertsByNameLengthQuery) error {
+	lengthExpr := "LENGTH(alert.name)"
+	if dialect.DriverName() == migrator.MYSQL {
+		// LENGTH counts bytes in MySQL
+		lengthExpr = "CHAR_LENGTH(alert.name)"
+	}
+
+	builder := SqlBuilder{}
+	builder.Write(`SELECT` + alertListItemColumns + `, ` + lengthExpr + ` AS name_length` + alertListItemFrom)
+	builder.Write(`WHERE alert.org_id = ? AND `+lengthExpr+` BETWEEN ? AND ?`, query.OrgId, query.MinLength, query.MaxLength)
+	builder.Write(` ORDER BY name_length DESC, name ASC`)
+
+	alerts := make([]*models.AlertNameLengthDTO, 0)
+	if err := x.SQL(builder.GetSqlString(), builder.params...).Find(&alerts); err != nil {
+		return err
+	}
+
+	for _, alert := range alerts {
+		cleanAlertListItem(&alert.AlertListItemDTO)
+
+		if name := []rune(alert.Name); len(name) > models.AlertNameMaxLength {
+			alert.SuggestedName = string(name[:models.AlertNameMaxLength])
+		}
+	}
+
+	query.Result = alerts
+	return nil
+}
+
+// GetAlertsByStateChangeSince returns the alerts whose state changed at or
+// after Since, most recent change first.
+func GetAlertsByStateChangeSince(query *models.GetAlertsByStateChangeSinceQuery) error {
+	builder := SqlBuilder{}
+	builder.Write(alertListItemSelect)
+	builder.Write(`WHERE alert.org_id = ? AND alert.new_state_date >= ?`, query.OrgId, query.Since)
+
+	if len(query.States) > 0 {
+		builder.Write(` AND alert.state IN (?` + strings.Repeat(",?", len(query.States)-1) + `)`)
+		for _, state := range query.States {
+			builder.AddParams(state)
+		}
+	}
+
+	builder.Write(` ORDER BY alert.new_state_date DESC`)
+
+	if query.Limit != 0 {
+		builder.Write(dialect.Limit(query.Limit))
+	}
+
+	alerts, err := findAlertListItems(&builder)
+	if err != nil {
+		return err
+	}
+
+	query.Result = alerts
+	return nil
+}
+
+// GetAlertDetail loads an alert with the uid and title of its dashboard, its
+// tags and the notification channels it references.
+func GetAlertDetail(query *models.GetAlertDetailQuery) error {
+	sess := newSession()
+	defer sess.Close()
+
+	alert := &models.Alert{}
+	has, err := sess.Where("id = ? AND org_id = ?", query.Id, query.OrgId).Get(alert)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return models.ErrAlertNotFound
+	}
+
+	detail := &models.AlertDetailDTO{Alert: alert}
+
+	dashboard := models.Dashboard{}
+	has, err = sess.Table("dashboard").Cols("uid", "title").Where("id = ?", alert.DashboardId).Get(&dashboard)
+	if err != nil {
+		return err
+	}
+	if has {
+		detail.DashboardUid = dashboard.Uid
+		detail.DashboardTitle = dashboard.Title
+	}
+
+	detail.Tags = make([]*models.Tag, 0)
+	rawSql := `SELECT tag.id, tag.` + dialect.Quote("key") + `, tag.` + dialect.Quote("value") + `
+		FROM tag
+		INNER JOIN alert_rule_tag ON alert_rule_tag.tag_id = tag.id
+		WHERE alert_rule_tag.alert_id = ?
+		ORDER BY tag.` + dialect.Quote("key") + ` ASC, tag.` + dialect.Quote("value") + ` ASC`
+	if err := sess.SQL(rawSql, alert.Id).Find(&detail.Tags); err != nil {
+		return err
+	}
+
+	detail.Notifications, err = getAlertNotificationRefs(alert, sess)
+	if err != nil {
+		return err
+	}
+
+	query.Result = detail
+	return nil
+}
+
+// ExportAlertsByFilter loads the alerts matching the filter together with
+// the uids of their dashboards and notification channels and their tags.
+// Each of them is loaded with a single query for the whole set of alerts.
+func ExportAlertsByFilter(query *models.ExportAlertsByFilterQuery) error {
+	sess := newSession()
+	defer sess.Close()
+
+	query.Result = make([]*models.AlertExportDTO, 0)
+
+	ids, err := getAlertIdsByFilter(query.OrgId, query.Filter, sess)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	alerts := make([]*models.Alert, 0)
+	if err := sess.In("id", ids).Asc("id").Find(&alerts); err != nil {
+		return err
+	}
+
+	dashboardIds := make([]int64, 0)
+	for _, alert := range alerts {
+		dashboardIds = append(dashboardIds, alert.DashboardId)
+	}
+	dashboards := make([]*models.Dashboard, 0)
+	if err := sess.Table("dashboard").Cols("id", "uid").In("id", dashboardIds).Find(&dashboards); err != nil {
+		return err
+	}
+	dashboardUids := make(map[int64]string, len(dashboards))
+	for _, dashboard := range dashboards {
+		dashboardUids[dashboard.Id] = dashboard.Uid
+	}
+
+	builder := SqlBuilder{}
+	builder.Write(`SELECT alert_rule_tag.alert_id, tag.` + dialect.Quote("key") + `, tag.` + dialect.Quote("value") + `
+		FROM alert_rule_tag
+		INNER JOIN tag ON tag.id = alert_rule_tag.tag_id
+		WHERE alert_rule_tag.alert_id IN (?` + strings.Repeat(",?", len(ids)-1) + `)`)
+	for _, id := range ids {
+		builder.AddParams(id)
+	}
+
+	type alertTag struct {
+		AlertId int64
+		Key     string
+		Value   string
+	}
+
+	alertTags := make([]*alertTag, 0)
+	if err := sess.SQL(builder.GetSqlString(), builder.params...).Find(&alertTags); err != nil {
+		return err
+	}
+
+	notifications, err := getAlertNotificationsByOrgId(query.OrgId, sess)
+	if err != nil {
+		return err
+	}
+
+	for _, alert := range alerts {
+		export := &models.AlertExportDTO{
+			DashboardUid:     dashboardUids[alert.DashboardId],
+			PanelId:          alert.PanelId,
+			Name:             alert.Name,
+			Message:          alert.Message,
+			Frequency:        alert.Frequency,
+			For:              alert.For,
+			Tags:             make(map[string]string),
+			NotificationUids: make([]string, 0),
+			Settings:         alert.Settings,
+		}
+
+		for _, tag := range alertTags {
+			if tag.AlertId == alert.Id {
+				export.Tags[tag.Key] = tag.Value
+			}
+		}
+
+		uidRefs := make([]*models.AlertNotificationRef, 0)
+		for _, ref := range alert.GetNotificationsFromSettings() {
+			for _, notification := range notifications {
+				if ref.Matches(notification) {
+					export.NotificationUids = append(export.NotificationUids, notification.Uid)
+					uidRefs = append(uidRefs, &models.AlertNotificationRef{Uid: notification.Uid})
+					break
+				}
+			}
+		}
+
+		if export.Settings == nil {
+			export.Settings = simplejson.New()
+		}
+		export.Settings.Set("notifications", notificationRefsToSettings(uidRefs))
+
+		query.Result = append(query.Result, export)
+	}
+
+	return nil
+}
+
+// getAlertNotificationRefs resolves the notification channels referenced by
+// the settings of an alert with a single query. References to channels that
+// no longer exist are skipped.
+func getAlertNotificationRefs(alert *models.Alert, sess *DBSession) ([]*models.AlertNotificationRefDTO, error) {
+	result := make([]*models.AlertNotificationRefDTO, 0)
+
+	refs := alert.GetNotificationsFromSettings()
+	if len(refs) == 0 {
+		return result, nil
+	}
+
+	var ids []interface{}
+	var uids []interface{}
+	for _, ref := range refs {
+		if ref.Uid != "" {
+			uids = append(uids, ref.Uid)
+		} else {
+			ids = append(ids, ref.Id)
+		}
+	}
+
+	builder := SqlBuilder{}
+	builder.Write(`SELECT * FROM alert_notification WHERE org_id = ? AND (`, alert.OrgId)
+	if len(ids) > 0 {
+		builder.Write(`id IN (?`+strings.Repeat(",?", len(ids)-1)+`)`, ids...)
+	}
+	if len(uids) > 0 {
+		if len(ids) > 0 {
+			builder.Write(` OR `)
+		}
+		builder.Write(`uid IN (?`+strings.Repeat(",?", len(uids)-1)+`)`, uids...)
+	}
+	builder.Write(`)`)
+
+	notifications := make([]*models.AlertNotification, 0)
+	if err := sess.SQL(builder.GetSqlString(), builder.params...).Find(&notifications); err != nil {
+		return nil, err
+	}
+
+	for _, ref := range refs {
+		for _, notification := range notifications {
+			if ref.Matches(notification) {
+				result = append(result, &models.AlertNotificationRefDTO{
+					Id:   notification.Id,
+					Uid:  notification.Uid,
+					Name: notification.Name,
+				})
+				break
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// GetAlertsByMultipleDashboardUids resolves the dashboard uids with a single
+// query and loads the alerts of all the resolved dashboards at once.
+func GetAlertsByMultipleDashboardUids(query *models.GetAlertsByMultipleDashboardUidsQuery) error {
+	query.Result = make(map[string][]*models.Alert)
+	query.UnresolvedUIDs = make([]string, 0)
+
+	if len(query.DashboardUIDs) == 0 {
+		return nil
+	}
+
+	sess := newSession()
+	defer sess.Close()
+
+	type dashboardRef struct {
+		Id  int64
+		Uid string
+	}
+
+	dashboards := make([]*dashboardRef, 0)
+	err := sess.Table("dashboard").Cols("id", "uid").
+		Where("org_id = ?", query.OrgId).
+		In("uid", query.DashboardUIDs).
+		Find(&dashboards)
+	if err != nil {
+		return err
+	}
+
+	uidsById := make(map[int64]string, len(dashboards))
+	ids := make([]int64, 0, len(dashboards))
+	for _, dashboard := range dashboards {
+		uidsById[dashboard.Id] = dashboard.Uid
+		ids = append(ids, dashboard.Id)
+		query.Result[dashboard.Uid] = make([]*models.Alert, 0)
+	}
+
+	for _, uid := range query.DashboardUIDs {
+		if _, ok := query.Result[uid]; !ok {
+			query.UnresolvedUIDs = append(query.UnresolvedUIDs, uid)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	alerts := make([]*models.Alert, 0)
+	if err := sess.Where("org_id = ?", query.OrgId).In("dashboard_id", ids).Asc("id").Find(&alerts); err != nil {
+		return err
+	}
+
+	for _, alert := range alerts {
+		uid := uidsById[alert.DashboardId]
+		query.Result[uid] = append(query.Result[uid], alert)
+	}
+
+	return nil
+}
+
+// GetAlertingCapacityForecast estimates how many evaluations per second the
+// scheduler will run over each forecast interval. Like the scheduler, it
+// skips paused alerts, raises frequencies to the configured minimum interval
+// and evaluates an alert on the seconds that are a multiple of its
+// frequency. The offsets the scheduler spreads alerts with only shift
+// evaluations by a few seconds and are ignored.
+func GetAlertingCapacityForecast(query *models.GetAlertingCapacityForecastQuery) error {
+	rawSql := `SELECT frequency, COUNT(*) AS count
+		FROM alert
+		WHERE org_id = ? AND state <> ?
+		GROUP BY frequency`
+
+	type frequencyCount struct {
+		Frequency int64
+		Count     int64
+	}
+
+	counts := make([]*frequencyCount, 0)
+	if err := x.SQL(rawSql, query.OrgId, models.AlertStatePaused).Find(&counts); err != nil {
+		return err
+	}
+
+	now := timeNow().Unix()
+
+	query.Result = make([]*models.CapacityForecast, 0, len(query.ForecastIntervals))
+	for _, interval := range query.ForecastIntervals {
+		forecast := &models.CapacityForecast{Interval: interval}
+
+		seconds := int64(interval / time.Second)
+		if seconds > 0 {
+			var evaluations int64
+			for _, c := range counts {
+				evaluations += scheduledEvaluations(c.Frequency, now, seconds) * c.Count
+			}
+			forecast.EstimatedEvalsPerSecond = float64(evaluations) / float64(seconds)
+		}
+
+		query.Result = append(query.Result, forecast)
+	}
+
+	return nil
+}
+
+// scheduledEvaluations returns how many times the scheduler evaluates an
+// alert with the given frequency in the window of seconds after from.
+func scheduledEvaluations(frequency int64, from int64, seconds int64) int64 {
+	// rules without a frequency are evaluated every minute
+	if frequency == 0 {
+		frequency = 60
+	}
+	if frequency < setting.AlertingMinInterval {
+		frequency = setting.AlertingMinInterval
+	}
+	if frequency <= 0 {
+		return 0
+	}
+	return (from+seconds)/frequency - from/frequency
+}
+
+// GetEvaluationSchedulePreview returns the alerts the scheduler will
+// evaluate within the window starting now. See GetAlertingCapacityForecast
+// for how the schedule is derived.
+func GetEvaluationSchedulePreview(query *models.GetEvaluationSchedulePreviewQuery) error {
+	window := query.Window
+	if window <= 0 {
+		window = time.Minute
+	}
+
+	type alertFrequency struct {
+		Id        int64
+		Frequency int64
+	}
+
+	alerts := make([]*alertFrequency, 0)
+	rawSql := `SELECT id, frequency FROM alert WHERE org_id = ? AND state <> ? ORDER BY id ASC`
+	if err := x.SQL(rawSql, query.OrgId, models.AlertStatePaused).Find(&alerts); err != nil {
+		return err
+	}
+
+	now := timeNow().Unix()
+	seconds := int64(window / time.Second)
+
+	query.DueAlertIds = make([]int64, 0)
+	for _, alert := range alerts {
+		if scheduledEvaluations(alert.Frequency, now, seconds) > 0 {
+			query.DueAlertIds = append(query.DueAlertIds, alert.Id)
+		}
+	}
+	query.DueCount = int64(len(query.DueAlertIds))
+
+	return nil
+}
+
+// GetAlertsWithDanglingNotifications returns the alerts whose settings
+// reference notification channels that have been deleted.
+func GetAlertsWithDanglingNotifications(query *models.GetAlertsWithDanglingNotificationsQuery) error {
+	sess := newSession()
+	defer sess.Close()
+
+	alerts, err := getAlertsByOrgId(query.OrgId, sess)
+	if err != nil {
+		return err
+	}
+
+	notifications, err := getAlertNotificationsByOrgId(query.OrgId, sess)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int64, 0)
+	dangling := make(map[int64][]*models.AlertNotificationRef)
+	for _, alert := range alerts {
+		if _, refs := partitionNotificationRefs(alert, notifications); len(refs) > 0 {
+			ids = append(ids, alert.Id)
+			dangling[alert.Id] = refs
+		}
+	}
+
+	items, err := getAlertListItemsByIds(query.OrgId, ids)
+	if err != nil {
+		return err
+	}
+
+	query.Result = make([]*models.AlertDanglingNotificationsDTO, 0, len(items))
+	for _, item := range items {
+		query.Result = append(query.Result, &models.AlertDanglingNotificationsDTO{
+			AlertListItemDTO:      *item,
+			DanglingNotifications: dangling[item.Id],
+		})
+	}
+
+	return nil
+}
+
+func getAlertNotificationsByOrgId(orgId int64, sess *DBSession) ([]*models.AlertNotification, error) {
+	notifications := make([]*models.AlertNotification, 0)
+	err := sess.Where("org_id = ?", orgId).Find(&notifications)
+	return notifications, err
+}
+
+// notificationRefsToSettings converts notification references back to the
+// form they are stored in the notifications setting of an alert.
+func notificationRefsToSettings(refs []*models.AlertNotificationRef) []interface{} {
+	notifications := make([]interface{}, 0, len(refs))
+	for _, ref := range refs {
+		if ref.Uid != "" {
+			notifications = append(notifications, map[string]interface{}{"uid": ref.Uid})
+		} else {
+			notifications = append(notifications, map[string]interface{}{"id": ref.Id})
+		}
+	}
+	return notifications
+}
+
+// partitionNotificationRefs splits the notification references of an alert
+// into the ones matching one of the given notification channels and the
+// dangling ones that match none.
+func partitionNotificationRefs(alert *models.Alert, notifications []*models.AlertNotification) (resolved, dangling []*models.AlertNotificationRef) {
+	resolved = make([]*models.AlertNotificationRef, 0)
+	dangling = make([]*models.AlertNotificationRef, 0)
+	for _, ref := range alert.GetNotificationsFromSettings() {
+		found := false
+		for _, notification := range notifications {
+			if ref.Matches(notification) {
+				found = true
+				break
+			}
+		}
+		if found {
+			resolved = append(resolved, ref)
+		} else {
+			dangling = append(dangling, ref)
+		}
+	}
+	return resolved, dangling
+}
+
+// GetAlertsByStateAndAge returns the alerts whose state is the given state
+// and last changed before OlderThan ago, and pauses them if asked to.
+func GetAlertsByStateAndAge(query *models.GetAlertsByStateAndAgeQuery) error {
+	builder := SqlBuilder{}
+	builder.Write(alertListItemSelect)
+	builder.Write(`WHERE alert.org_id = ? AND alert.state = ? AND alert.new_state_date < ?`,
+		query.OrgId, query.State, timeNow().Add(-query.OlderThan))
+	builder.Write(" ORDER BY alert.new_state_date ASC")
+
+	alerts, err := findAlertListItems(&builder)
+	if err != nil {
+		return err
+	}
+
+	if query.AutoPause && len(alerts) > 0 {
+		cmd := &models.PauseAlertCommand{OrgId: query.OrgId, Paused: true}
+		for _, alert := range alerts {
+			cmd.AlertIds = append(cmd.AlertIds, alert.Id)
+		}
+		if err := PauseAlert(cmd); err != nil {
+			return err
+		}
+	}
+
+	query.Result = alerts
+	return nil
+}
+
+// GetAlertsForChannelGroup returns the alerts sending to at least one of the
+// channels of a group, with the uids of the channels of the group they send
+// to. Channels are resolved first so that alerts referencing a channel by id
+// are found as well.
+func GetAlertsForChannelGroup(query *models.GetAlertsForChannelGroupQuery) error {
+	query.Result = make([]*models.AlertChannelGroupDTO, 0)
+	if len(query.ChannelUids) == 0 {
+		return nil
+	}
+
+	sess := newSession()
+	defer sess.Close()
+
+	notifications := make([]*models.AlertNotification, 0)
+	if err := sess.Where("org_id = ?", query.OrgId).In("uid", query.ChannelUids).Find(&notifications); err != nil {
+		return err
+	}
+
+	alerts, err := getAlertsByOrgId(query.OrgId, sess)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int64, 0)
+	channels := make(map[int64][]string)
+	for _, alert := range alerts {
+		resolved, _ := partitionNotificationRefs(alert, notifications)
+		for _, notification := range notifications {
+			for _, ref := range resolved {
+				if ref.Matches(notification) {
+					channels[alert.Id] = append(channels[alert.Id], notification.Uid)
+					break
+				}
+			}
+		}
+		if len(channels[alert.Id]) > 0 {
+			ids = append(ids, alert.Id)
+		}
+	}
+
+	items, err := getAlertListItemsByIds(query.OrgId, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		query.Result = append(query.Result, &models.AlertChannelGroupDTO{
+			AlertListItemDTO: *item,
+			ChannelUids:      channels[item.Id],
+		})
+	}
+
+	return nil
+}
+
+// GetAlertsByPanel returns the alerts on the panel with the given id on any
+// dashboard of the org, ordered by dashboard.
+func GetAlertsByPanel(query *models.GetAlertsByPanelQuery) error {
+	builder := SqlBuilder{}
+	builder.Write(alertListItemSelect)
+	builder.Write(`WHERE alert.org_id = ? AND alert.panel_id = ?`, query.OrgId, query.PanelId)
+	builder.Write(" ORDER BY alert.dashboard_id ASC")
+
+	alerts, err := findAlertListItems(&builder)
+	if err != nil {
+		return err
+	}
+
+	query.Result = alerts
+	return nil
+}
+
+// GetAlertsWithSharedPanelId returns the panel ids used by alerts on more
+// than one dashboard of the org, with the alerts using each of them.
+func GetAlertsWithSharedPanelId(query *models.GetAlertsWithSharedPanelIdQuery) error {
+	rawSql := `SELECT panel_id
+		FROM alert
+		WHERE org_id = ?
+		GROUP BY panel_id
+		HAVING COUNT(DISTINCT dashboard_id) > 1`
+
+	type sharedPanel struct {
+		PanelId int64
+	}
+
+	panels := make([]*sharedPanel, 0)
+	if err := x.SQL(rawSql, query.OrgId).Find(&panels); err != nil {
+		return err
+	}
+
+	query.Result = make([]*models.AlertSharedPanelDTO, 0, len(panels))
+	if len(panels) == 0 {
+		return nil
+	}
+
+	builder := SqlBuilder{}
+	builder.Write(alertListItemSelect)
+	builder.Write(`WHERE alert.org_id = ? AND alert.panel_id IN (?`+strings.Repeat(",?", len(panels)-1)+`)`, query.OrgId)
+	for _, panel := range panels {
+		builder.AddParams(panel.PanelId)
+	}
+	builder.Write(" ORDER BY alert.panel_id ASC, alert.dashboard_id ASC")
+
+	alerts, err := findAlertListItems(&builder)
+	if err != nil {
+		return err
+	}
+
+	var current *models.AlertSharedPanelDTO
+	for _, alert := range alerts {
+		if current == nil || current.PanelId != alert.PanelId {
+			current = &models.AlertSharedPanelDTO{PanelId: alert.PanelId}
+			query.Result = append(query.Result, current)
+		}
+		current.Alerts = append(current.Alerts, alert)
+	}
+
+	return nil
+}
+
+// GetAlertsBySettingsKey returns the alerts whose settings have the given
+// top level key, whatever its value. Postgres and MySQL check the key in
+// the database, SQLite has no JSON support to rely on so the settings are
+// decoded and checked here.
+func GetAlertsBySettingsKey(query *models.GetAlertsBySettingsKeyQuery) error {
+	builder := SqlBuilder{}
+	builder.Write(alertListItemSelect)
+	builder.Write(`WHERE alert.org_id = ?`, query.OrgId)
+
+	switch dialect.DriverName() {
+	case migrator.POSTGRES:
+		builder.Write(` AND (alert.settings::jsonb -> ?) IS NOT NULL`, query.Key)
+	case migrator.MYSQL:
+		builder.Write(` AND JSON_EXTRACT(alert.settings, ?) IS NOT NULL`, fmt.Sprintf(`$.%q`, query.Key))
+	default:
+		sess := newSession()
+		defer sess.Close()
+
+		alerts, err := getAlertsByOrgId(query.OrgId, sess)
+		if err != nil {
+			return err
+		}
+
+		ids := make([]int64, 0)
+		for _, alert := range alerts {
+			if alert.Settings == nil {
+				continue
+			}
+			if _, ok := alert.Settings.CheckGet(query.Key); ok {
+				ids = append(ids, alert.Id)
+			}
+		}
+
+		query.Result, err = getAlertListItemsByIds(query.OrgId, ids)
+		return err
+	}
+
+	builder.Write(" ORDER BY name ASC")
+
+	alerts, err := findAlertListItems(&builder)
+	if err != nil {
+		return err
+	}
+
+	query.Result = alerts
+	return nil
+}
+
+// GetAlertsByAnnotationCount counts the annotations per alert in a subquery
+// so the alert list columns don't have to be grouped by.
+func GetAlertsByAnnotationCount(query *models.GetAlertsByAnnotationCountQuery) error {
+	builder := SqlBuilder{}
+	builder.Write(`SELECT` + alertListItemColumns + `, annotation_counts.annotation_count` + alertListItemFrom)
+	// HAVING repeats the aggregate since Postgres does not allow column aliases there
+	builder.Write(`INNER JOIN (
+			SELECT alert_id, COUNT(*) AS annotation_count
+			FROM annotation
+			WHERE org_id = ? AND alert_id > 0
+			GROUP BY alert_id
+			HAVING COUNT(*) >= ?
+		) AS annotation_counts ON annotation_counts.alert_id = alert.id `, query.OrgId, query.MinAnnotations)
+	builder.Write(`WHERE alert.org_id = ?`, query.OrgId)
+	builder.Write(` ORDER BY annotation_counts.annotation_count DESC, alert.id ASC`)
+
+	alerts := make([]*models.AlertAnnotationCountDTO, 0)
+	if err := x.SQL(builder.GetSqlString(), builder.params...).Find(&alerts); err != nil {
+		return err
+	}
+
+	for _, alert := range alerts {
+		cleanAlertListItem(&alert.AlertListItemDTO)
+	}
+
+	query.Result = alerts
+	return nil
+}
+
+// GetOrgsWithAlertStateCountAboveThreshold returns, across all orgs, the
+// orgs with at least Threshold alerts in the given state.
+func GetOrgsWithAlertStateCountAboveThreshold(query *models.GetOrgsWithAlertStateCountAboveThresholdQuery) error {
+	// HAVING repeats the aggregate since Postgres does not allow column aliases there
+	rawSql := `SELECT org_id, COUNT(*) AS count
+		FROM alert
+		WHERE state = ?
+		GROUP BY org_id
+		HAVING COUNT(*) >= ?
+		ORDER BY count DESC, org_id ASC`
+
+	counts := make([]*models.OrgAlertStateCount, 0)
+	if err := x.SQL(rawSql, query.State, query.Threshold).Find(&counts); err != nil {
+		return err
+	}
+
+	query.Result = counts
+	return nil
+}
+
+func GetAlertNamesForDashboard(query *models.GetAlertNamesForDashboardQuery) error {
+	rawSql := `SELECT id, name, panel_id FROM alert WHERE org_id = ? AND dashboard_id = ? ORDER BY name ASC`
+
+	names := make([]*models.AlertNameDTO, 0)
+	if err := x.SQL(rawSql, query.OrgId, query.DashboardId).Find(&names); err != nil {
+		return err
+	}
+
+	query.Result = names
+	return nil
+}
+
+// GetAlertsByNewStateDateHour counts the alerts per hour of their last state
+// change between From and To. Postgres and MySQL group in the database,
+// SQLite has no portable way to truncate dates so its rows are bucketed
+// here. Hours without changes are left out.
+func GetAlertsByNewStateDateHour(query *models.GetAlertsByNewStateDateHourQuery) error {
+	var hourExpr string
+	switch dialect.DriverName() {
+	case migrator.POSTGRES:
+		hourExpr = `TO_CHAR(DATE_TRUNC('hour', new_state_date), 'YYYY-MM-DD HH24:MI:SS')`
+	case migrator.MYSQL:
+		hourExpr = `DATE_FORMAT(new_state_date, '%Y-%m-%d %H:00:00')`
+	default:
+		return getAlertsByNewStateDateHourInGo(query)
+	}
+
+	rawSql := `SELECT ` + hourExpr + ` AS hour_start, COUNT(*) AS change_count
+		FROM alert
+		WHERE org_id = ? AND new_state_date >= ? AND new_state_date < ?
+		GROUP BY ` + hourExpr + `
+		ORDER BY hour_start ASC`
+
+	type hourCount struct {
+		HourStart   string
+		ChangeCount int64
+	}
+
+	counts := make([]*hourCount, 0)
+	if err := x.SQL(rawSql, query.OrgId, query.From, query.To).Find(&counts); err != nil {
+		return err
+	}
+
+	query.Result = make([]*models.AlertStateHourBucket, 0, len(counts))
+	for _, c := range counts {
+		hourStart, err := time.Parse("2006-01-02 15:04:05", c.HourStart)
+		if err != nil {
+			return err
+		}
+		query.Result = append(query.Result, &models.AlertStateHourBucket{HourStart: hourStart, ChangeCount: c.ChangeCount})
+	}
+
+	return nil
+}
+
+func getAlertsByNewStateDateHourInGo(query *models.GetAlertsByNewStateDateHourQuery) error {
+	rawSql := `SELECT new_state_date FROM alert WHERE org_id = ? AND new_state_date >= ? AND new_state_date < ?`
+
+	type stateDate struct {
+		NewStateDate time.Time
+	}
+
+	dates := make([]*stateDate, 0)
+	if err := x.SQL(rawSql, query.OrgId, query.From, query.To).Find(&dates); err != nil {
+		return err
+	}
+
+	counts := make(map[time.Time]int64)
+	for _, d := range dates {
+		counts[d.NewStateDate.UTC().Truncate(time.Hour)]++
+	}
+
+	query.Result = make([]*models.AlertStateHourBucket, 0, len(counts))
+	for hourStart, count := range counts {
+		query.Result = append(query.Result, &models.AlertStateHourBucket{HourStart: hourStart, ChangeCount: count})
+	}
+	sort.Slice(query.Result, func(i, j int) bool { return query.Result[i].HourStart.Before(query.Result[j].HourStart) })
+
+	return nil
+}
+
+// GetFiringByHourOfDay counts the transitions to alerting recorded as state
+// change annotations between From and To per hour of the day in UTC.
+func GetFiringByHourOfDay(query *models.GetFiringByHourOfDayQuery) error {
+	rawSql := `SELECT epoch
+		FROM annotation
+		WHERE org_id = ? AND alert_id > 0 AND new_state = ? AND epoch >= ? AND epoch < ?`
+
+	type transition struct {
+		Epoch int64
+	}
+
+	from := query.From.UnixNano() / int64(time.Millisecond)
+	to := query.To.UnixNano() / int64(time.Millisecond)
+
+	transitions := make([]*transition, 0)
+	if err := x.SQL(rawSql, query.OrgId, models.AlertStateAlerting, from, to).Find(&transitions); err != nil {
+		return err
+	}
+
+	query.Result = make([]*models.AlertHourOfDayCount, 24)
+	for hour := range query.Result {
+		query.Result[hour] = &models.AlertHourOfDayCount{Hour: hour}
+	}
+
+	for _, t := range transitions {
+		hour := time.Unix(0, t.Epoch*int64(time.Millisecond)).UTC().Hour()
+		query.Result[hour].Count++
+	}
+
+	return nil
+}
+
+// GetAlertsByConditionOperator returns the alerts combining at least two of
+// their conditions with the given operator.
+func GetAlertsByConditionOperator(query *models.GetAlertsByConditionOperatorQuery) error {
+	if query.Operator != "and" && query.Operator != "or" {
+		return models.ErrInvalidConditionOperator
+	}
+
+	sess := newSession()
+	defer sess.Close()
+
+	alerts, err := getAlertsByOrgId(query.OrgId, sess)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int64, 0)
+	for _, alert := range alerts {
+		if alertUsesConditionOperator(alert, query.Operator) {
+			ids = append(ids, alert.Id)
+		}
+	}
+
+	query.Result, err = getAlertListItemsByIds(query.OrgId, ids)
+	return err
+}
+
+func alertUsesConditionOperator(alert *models.Alert, operator string) bool {
+	if alert.Settings == nil {
+		return false
+	}
+
+	for i, condition := range alert.Settings.Get("conditions").MustArray() {
+		// the evaluator ignores the operator of the first condition
+		if i == 0 {
+			continue
+		}
+		if simplejson.NewFromAny(condition).GetPath("operator", "type").MustString("and") == operator {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetAlertNamingViolations returns the alerts whose name does not start with
+// the value of their TagKey tag, with the prefix they were expected to have.
+func GetAlertNamingViolations(query *models.GetAlertNamingViolationsQuery) error {
+	if query.TagKey == "" {
+		return models.ErrAlertNamingTagKeyRequired
+	}
+
+	sess := newSession()
+	defer sess.Close()
+
+	alerts, err := getAlertsByOrgId(query.OrgId, sess)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int64, 0)
+	expected := make(map[int64]string)
+	for _, alert := range alerts {
+		prefix := ""
+		for _, tag := range alert.GetTagsFromSettings() {
+			if tag.Key == query.TagKey {
+				prefix = tag.Value
+				break
+			}
+		}
+
+		if prefix == "" || !strings.HasPrefix(alert.Name, prefix) {
+			ids = append(ids, alert.Id)
+			expected[alert.Id] = prefix
+		}
+	}
+
+	items, err := getAlertListItemsByIds(query.OrgId, ids)
+	if err != nil {
+		return err
+	}
+
+	query.Result = make([]*models.AlertNamingViolationDTO, 0, len(items))
+	for _, item := range items {
+		query.Result = append(query.Result, &models.AlertNamingViolationDTO{
+			AlertListItemDTO: *item,
+			ExpectedPrefix:   expected[item.Id],
+		})
+	}
+
+	return nil
+}
+
+// GetAlertsByEvalDataValue returns the alerts whose last state change
+// matched the metric with a value within the bounds of the query. Eval data
+// is stored as text holding an evalMatches array, which cannot be cast to
+// JSON safely in every database, so only the ids and eval data are loaded
+// and matched here.
+func GetAlertsByEvalDataValue(query *models.GetAlertsByEvalDataValueQuery) error {
+	type alertEvalData struct {
+		Id       int64
+		EvalData *simplejson.Json
+	}
+
+	rows := make([]*alertEvalData, 0)
+	rawSql := `SELECT id, eval_data FROM alert WHERE org_id = ? AND eval_data LIKE ?`
+	if err := x.SQL(rawSql, query.OrgId, `%"evalMatches"%`).Find(&rows); err != nil {
+		return err
+	}
+
+	ids := make([]int64, 0)
+	values := make(map[int64]float64)
+	for _, row := range rows {
+		if row.EvalData == nil {
+			continue
+		}
+
+		for _, match := range row.EvalData.Get("evalMatches").MustArray() {
+			jsonMatch := simplejson.NewFromAny(match)
+			if jsonMatch.Get("metric").MustString() != query.MetricName {
+				continue
+			}
+
+			value, err := jsonMatch.Get("value").Float64()
+			if err != nil {
+				// null values
+				continue
+			}
+			if query.GtValue != nil && value <= *query.GtValue {
+				continue
+			}
+			if query.LtValue != nil && value >= *query.LtValue {
+				continue
+			}
+
+			ids = append(ids, row.Id)
+			values[row.Id] = value
+			break
+		}
+	}
+
+	items, err := getAlertListItemsByIds(query.OrgId, ids)
+	if err != nil {
+		return err
+	}
+
+	query.Result = make([]*models.AlertEvalDataValueDTO, 0, len(items))
+	for _, item := range items {
+		query.Result = append(query.Result, &models.AlertEvalDataValueDTO{
+			AlertListItemDTO: *item,
+			MatchingValue:    values[item.Id],
+		})
+	}
+
+	return nil
+}
+
+// GetAlertsByOrgFiringDuration returns the alerting alerts of an org with
+// how long they have been firing, longest first.
+func GetAlertsByOrgFiringDuration(query *models.GetAlertsByOrgFiringDurationQuery) error {
+	type firingAlert struct {
+		Id           int64
+		Name         string
+		NewStateDate time.Time
+	}
+
+	alerts := make([]*firingAlert, 0)
+	rawSql := `SELECT id, name, new_state_date FROM alert WHERE org_id = ? AND state = ? ORDER BY new_state_date ASC, id ASC`
+	if err := x.SQL(rawSql, query.OrgId, models.AlertStateAlerting).Find(&alerts); err != nil {
+		return err
+	}
+
+	now := timeNow()
+	query.Result = make([]*models.AlertFiringDurationDTO, 0, len(alerts))
+	for _, alert := range alerts {
+		query.Result = append(query.Result, &models.AlertFiringDurationDTO{
+			AlertId:        alert.Id,
+			AlertName:      alert.Name,
+			FiringDuration: now.Sub(alert.NewStateDate),
+		})
+	}
+
+	return nil
+}
+
+// GetAlertsWithBlankName returns the alerts whose name is empty or only
+// made of spaces. Saving a dashboard rejects such names, but alerts saved
+// before remain.
+func GetAlertsWithBlankName(query *models.GetAlertsWithBlankNameQuery) error {
+	builder := SqlBuilder{}
+	builder.Write(alertListItemSelect)
+	builder.Write(`WHERE alert.org_id = ? AND TRIM(alert.name) = ''`, query.OrgId)
+	builder.Write(" ORDER BY alert.dashboard_id ASC, alert.panel_id ASC")
+
+	alerts, err := findAlertListItems(&builder)
+	if err != nil {
+		return err
+	}
+
+	query.Result = alerts
+	return nil
+}
+
+// GetDuplicateAlertsOnDashboard groups the alerts of a dashboard that have
+// the same name and settings but are on different panels, which happens
+// when a panel is copied.
+func GetDuplicateAlertsOnDashboard(query *models.GetDuplicateAlertsOnDashboardQuery) error {
+	sess := newSession()
+	defer sess.Close()
+
+	alerts := make([]*models.Alert, 0)
+	if err := sess.Where("org_id = ? AND dashboard_id = ?", query.OrgId, query.DashboardId).Asc("panel_id").Find(&alerts); err != nil {
+		return err
+	}
+
+	type alertKey struct {
+		name     string
+		settings string
+	}
+
+	groups := make(map[alertKey][]*models.Alert)
+	keys := make([]alertKey, 0)
+	for _, alert := range alerts {
+		settings, err := encodeAlertSettings(alert)
+		if err != nil {
+			return err
+		}
+
+		key := alertKey{name: alert.Name, settings: string(settings)}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], alert)
+	}
+
+	query.Result = make([]*models.DuplicateAlertGroupDTO, 0)
+	for _, key := range keys {
+		if len(groups[key]) > 1 {
+			query.Result = append(query.Result, &models.DuplicateAlertGroupDTO{Name: key.name, Alerts: groups[key]})
+		}
+	}
+
+	return nil
+}
+
+// CompareOrgAlerts matches the alerts of two orgs by name and reports the
+// names found in only one org and the alerts whose settings differ. Names
+// used by several alerts of an org cannot be matched and are reported as
+// ambiguous.
+func CompareOrgAlerts(query *models.CompareOrgAlertsQuery) error {
+	sess := newSession()
+	defer sess.Close()
+
+	alertsA, err := getAlertsByOrgId(query.OrgIdA, sess)
+	if err != nil {
+		return err
+	}
+	alertsB, err := getAlertsByOrgId(query.OrgIdB, sess)
+	if err != nil {
+		return err
+	}
+	notificationsA, err := getAlertNotificationsByOrgId(query.OrgIdA, sess)
+	if err != nil {
+		return err
+	}
+	notificationsB, err := getAlertNotificationsByOrgId(query.OrgIdB, sess)
+	if err != nil {
+		return err
+	}
+
+	byNameA := groupAlertsByName(alertsA)
+	byNameB := groupAlertsByName(alertsB)
+
+	names := make([]string, 0, len(byNameA)+len(byNameB))
+	for name := range byNameA {
+		names = append(names, name)
+	}
+	for name := range byNameB {
+		if _, ok := byNameA[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	result := &models.OrgAlertsDriftDTO{
+		OnlyInA:        make([]string, 0),
+		OnlyInB:        make([]string, 0),
+		Differing:      make([]*models.AlertDriftDTO, 0),
+		AmbiguousNames: make([]string, 0),
+	}
+
+	for _, name := range names {
+		a, b := byNameA[name], byNameB[name]
+		switch {
+		case len(a) > 1 || len(b) > 1:
+			result.AmbiguousNames = append(result.AmbiguousNames, name)
+		case len(b) == 0:
+			result.OnlyInA = append(result.OnlyInA, name)
+		case len(a) == 0:
+			result.OnlyInB = append(result.OnlyInB, name)
+		default:
+			settingsA, err := encodeComparableAlertSettings(a[0], notificationsA)
+			if err != nil {
+				return err
+			}
+			settingsB, err := encodeComparableAlertSettings(b[0], notificationsB)
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(settingsA, settingsB) {
+				result.Differing = append(result.Differing, &models.AlertDriftDTO{Name: name, AlertIdA: a[0].Id, AlertIdB: b[0].Id})
+			}
+		}
+	}
+
+	query.Result = result
+	return nil
+}
+
+// encodeAlertSettings returns the settings of an alert as JSON, or nil for
+// an alert without settings.
+func encodeAlertSettings(alert *models.Alert) ([]byte, error) {
+	if alert.Settings == nil {
+		return nil, nil
+	}
+	return alert.Settings.Encode()
+}
+
+// encodeComparableAlertSettings returns the settings of an alert as JSON
+// without the values that differ between orgs for the same alert, or nil
+// for an alert without settings. The datasource ids of the conditions are
+// left out and the notifications are referenced by uid, like in exports.
+func encodeComparableAlertSettings(alert *models.Alert, notifications []*models.AlertNotification) ([]byte, error) {
+	raw, err := encodeAlertSettings(alert)
+	if raw == nil || err != nil {
+		return nil, err
+	}
+	settings, err := simplejson.NewJson(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, condition := range settings.Get("conditions").MustArray() {
+		simplejson.NewFromAny(condition).Get("query").Del("datasourceId")
+	}
+
+	if _, ok := settings.CheckGet("notifications"); ok {
+		refs := make([]*models.AlertNotificationRef, 0)
+		for _, ref := range alert.GetNotificationsFromSettings() {
+			for _, notification := range notifications {
+				if ref.Matches(notification) {
+					ref = &models.AlertNotificationRef{Uid: notification.Uid}
+					break
+				}
+			}
+			refs = append(refs, ref)
+		}
+		settings.Set("notifications", notificationRefsToSettings(refs))
+	}
+
+	return settings.Encode()
+}
+
+func groupAlertsByName(alerts []*models.Alert) map[string][]*models.Alert {
+	byName := make(map[string][]*models.Alert)
+	for _, alert := range alerts {
+		byName[alert.Name] = append(byName[alert.Name], alert)
+	}
+	return byName
+}
+
+// GetAlertsByTagCountDistribution counts the alerts of an org per number of
+// tags they have, including the alerts without tags.
+func GetAlertsByTagCountDistribution(query *models.GetAlertsByTagCountDistributionQuery) error {
+	rawSql := `SELECT tag_count, COUNT(*) AS alert_count
+		FROM (
+			SELECT alert.id, COUNT(alert_rule_tag.tag_id) AS tag_count
+			FROM alert
+			LEFT JOIN alert_rule_tag ON alert_rule_tag.alert_id = alert.id
+			WHERE alert.org_id = ?
+			GROUP BY alert.id
+		) alert_tag_count
+		GROUP BY tag_count
+		ORDER BY tag_count ASC`
+
+	buckets := make([]*models.TagCountBucket, 0)
+	if err := x.SQL(rawSql, query.OrgId).Find(&buckets); err != nil {
+		return err
+	}
+
+	query.Result = buckets
+	return nil
+}
+
+// getAlertListItemsByIds returns the alert list items for the given alert ids,
+// used by queries that have to filter alerts outside of the database.
+func getAlertListItemsByIds(orgId int64, ids []int64) ([]*models.AlertListItemDTO, error) {
+	if len(ids) == 0 {
+		return make([]*models.AlertListItemDTO, 0), nil
+	}
+
+	builder := SqlBuilder{}
+	builder.Write(alertListItemSelect)
+	builder.Write(`WHERE alert.org_id = ? AND alert.id IN (?`+strings.Repeat(",?", len(ids)-1)+`)`, orgId)
+	for _, id := range ids {
+		builder.AddParams(id)
+	}
+	builder.Write(" ORDER BY name ASC")
+
+	return findAlertListItems(&builder)
+}
+
+// GetDatasourceAlertErrorRates reads the data sources of the alerts from the
+// conditions in their settings. An alert querying several data sources is
+// counted for each of them. Highest error rate first.
+func GetDatasourceAlertErrorRates(query *models.GetDatasourceAlertErrorRatesQuery) error {
+	sess := newSession()
+	defer sess.Close()
+
+	alerts, err := getAlertsByOrgId(query.OrgId, sess)
+	if err != nil {
+		return err
+	}
+
+	datasources := make([]*models.DataSource, 0)
+	if err := sess.Table("data_source").Cols("id", "name").Where("org_id = ?", query.OrgId).Find(&datasources); err != nil {
+		return err
+	}
+	names := make(map[int64]string, len(datasources))
+	for _, ds := range datasources {
+		names[ds.Id] = ds.Name
+	}
+
+	rates := make(map[int64]*models.DatasourceAlertErrorRateDTO)
+	for _, alert := range alerts {
+		if alert.Settings == nil {
+			continue
+		}
+
+		erroring := strings.TrimSpace(alert.ExecutionError) != ""
+		seen := make(map[int64]bool)
+		for _, condition := range alert.Settings.Get("conditions").MustArray() {
+			datasourceId := simplejson.NewFromAny(condition).GetPath("query", "datasourceId").MustInt64()
+			if datasourceId == 0 || seen[datasourceId] {
+				continue
+			}
+			seen[datasourceId] = true
+
+			rate, ok := rates[datasourceId]
+			if !ok {
+				rate = &models.DatasourceAlertErrorRateDTO{DatasourceId: datasourceId, DatasourceName: names[datasourceId]}
+				rates[datasourceId] = rate
+			}
+			rate.AlertCount++
+			if erroring {
+				rate.ErroringCount++
+			}
+		}
+	}
+
+	query.Result = make([]*models.DatasourceAlertErrorRateDTO, 0, len(rates))
+	for _, rate := range rates {
+		rate.ErrorRate = float64(rate.ErroringCount) / float64(rate.AlertCount)
+		query.Result = append(query.Result, rate)
+	}
+
+	sort.Slice(query.Result, func(i, j int) bool {
+		if query.Result[i].ErrorRate != query.Result[j].ErrorRate {
+			return query.Result[i].ErrorRate > query.Result[j].ErrorRate
+		}
+		return query.Result[i].DatasourceId < query.Result[j].DatasourceId
+	})
+
+	return nil
+}
+
+func getAlertsByOrgId(orgId int64, sess *DBSession) ([]*models.Alert, error) {
+	alerts := make([]*models.Alert, 0)
+	if err := sess.Where("org_id = ?", orgId).Asc("id").Find(&alerts); err != nil {
+		return nil, err
+	}
+
+	return alerts, nil
+}
+
+func DeleteAlertsByNamePrefix(cmd *models.DeleteAlertsByNamePrefixCommand) error {
+	if cmd.Prefix == "" {
+		return models.ErrAlertNamePrefixRequired
+	}
+
+	return inTransaction(func(sess *DBSession) error {
+		alertIds := []struct {
+			Id int64
+		}{}
+
+		rawSql := `SELECT id FROM alert WHERE org_id = ? AND name LIKE ?` + likeEscape
+		if err := sess.SQL(rawSql, cmd.OrgId, escapeLikePattern(cmd.Prefix)+"%").Find(&alertIds); err != nil {
+			return err
+		}
+
+		if cmd.DryRun {
+			cmd.WouldDeleteCount = int64(len(alertIds))
+			return nil
+		}
+
+		safetyCap := cmd.SafetyCap
+		if safetyCap <= 0 {
+			safetyCap = defaultAlertDeleteSafetyCap
+		}
+
+		if int64(len(alertIds)) > safetyCap {
+			return models.ErrAlertDeleteSafetyCapExceeded
+		}
+
+		for _, alert := range alertIds {
+			if err := deleteAlertByIdInternal(alert.Id, "Deleted by name prefix", sess); err != nil {
+				return err
+			}
+		}
+
+		cmd.DeletedCount = int64(len(alertIds))
+		return nil
+	})
+}
+
+func GetAlertsByForZero(query *models.GetAlertsByForZeroQuery) error {
+	builder := SqlBuilder{}
+	builder.Write(alertListItemSelect)
+	// for was added as a nullable column, alerts saved before have NULL
+	builder.Write(`WHERE alert.org_id = ? AND (alert.`+dialect.Quote("for")+` = 0 OR alert.`+dialect.Quote("for")+` IS NULL)`, query.OrgId)
+
+	if !query.IncludePaused {
+		builder.Write(` AND alert.state <> ?`, models.AlertStatePaused)
+	}
+
+	builder.Write(" ORDER BY name ASC")
+
+	alerts, err := findAlertListItems(&builder)
+	if err != nil {
+		return err
+	}
+
+	query.Result = alerts
+	return nil
+}
+
+// GetAlertsWithFewEvaluationsInFor finds the alerts with a For duration
+// covering fewer than MinEvaluations evaluations, fewest first. The
+// evaluations are counted with the frequency the scheduler actually uses.
+func GetAlertsWithFewEvaluationsInFor(query *models.GetAlertsWithFewEvaluationsInForQuery) error {
+	builder := SqlBuilder{}
+	builder.Write(`SELECT` + alertListItemColumns + `, alert.frequency, alert.` + dialect.Quote("for") + ` AS for_duration` + alertListItemFrom)
+	builder.Write(`WHERE alert.org_id = ? AND alert.`+dialect.Quote("for")+` > 0`, query.OrgId)
+
+	alerts := make([]*models.AlertEvaluationsInForDTO, 0)
+	if err := x.SQL(builder.GetSqlString(), builder.params...).Find(&alerts); err != nil {
+		return err
+	}
+
+	query.Result = make([]*models.AlertEvaluationsInForDTO, 0)
+	for _, alert := range alerts {
+		alert.EvaluationsInFor = scheduledEvaluations(alert.Frequency, 0, int64(alert.ForDuration/time.Second))
+		if alert.EvaluationsInFor >= query.MinEvaluations {
+			continue
+		}
+
+		cleanAlertListItem(&alert.AlertListItemDTO)
+		query.Result = append(query.Result, alert)
+	}
+
+	sort.SliceStable(query.Result, func(i, j int) bool {
+		if query.Result[i].EvaluationsInFor != query.Result[j].EvaluationsInFor {
+			return query.Result[i].EvaluationsInFor < query.Result[j].EvaluationsInFor
+		}
+		return query.Result[i].Name < query.Result[j].Name
+	})
+
+	return nil
+}
+
+// GetOverduePendingAlerts compares the new state date of the pending alerts
+// with their For duration in Go, since the duration is stored in nanoseconds
+// and date arithmetic differs between the databases. Most overdue first.
+func GetOverduePendingAlerts(query *models.GetOverduePendingAlertsQuery) error {
+	builder := SqlBuilder{}
+	builder.Write(`SELECT` + alertListItemColumns + `, alert.` + dialect.Quote("for") + ` AS for_duration` + alertListItemFrom)
+	builder.Write(`WHERE alert.org_id = ? AND alert.state = ?`, query.OrgId, models.AlertStatePending)
+
+	alerts := make([]*models.AlertOverduePendingDTO, 0)
+	if err := x.SQL(builder.GetSqlString(), builder.params...).Find(&alerts); err != nil {
+		return err
+	}
+
+	now := timeNow()
+	query.Result = make([]*models.AlertOverduePendingDTO, 0)
+	for _, alert := range alerts {
+		alert.Overdue = now.Sub(alert.NewStateDate.Add(alert.ForDuration))
+		if alert.Overdue <= 0 {
+			continue
+		}
+
+		cleanAlertListItem(&alert.AlertListItemDTO)
+		query.Result = append(query.Result, alert)
+	}
+
+	sort.SliceStable(query.Result, func(i, j int) bool {
+		return query.Result[i].Overdue > query.Result[j].Overdue
+	})
+
+	return nil
+}
+
+func GetAlertsByEvalDataNull(query *models.GetAlertsByEvalDataNullQuery) error {
+	builder := SqlBuilder{}
+	builder.Write(alertListItemSelect)
+	builder.Write(`WHERE alert.org_id = ? AND alert.eval_data IS NULL`, query.OrgId)
+
+	if !query.IncludePaused {
+		builder.Write(` AND alert.state <> ?`, models.AlertStatePaused)
+	}
+
+	builder.Write(" ORDER BY alert.id ASC")
+
+	alerts, err := findAlertListItems(&builder)
+	if err != nil {
+		return err
+	}
+
+	query.Result = alerts
+	return nil
+}
+
+func GetAlertsByDashboardPermission(query *models.GetAlertsByDashboardPermissionQuery) error {
+	switch query.PermissionLevel {
+	case models.PERMISSION_VIEW, models.PERMISSION_EDIT, models.PERMISSION_ADMIN:
+	default:
+		return models.ErrInvalidAlertPermissionLevel
+	}
+
+	builder := SqlBuilder{}
+	builder.Write(alertListItemSelect)
+	builder.Write(`WHERE alert.org_id = ?`, query.OrgId)
+	builder.writeDashboardPermissionFilter(query.User, query.PermissionLevel)
+	builder.Write(" ORDER BY name ASC")
+
+	alerts, err := findAlertListItems(&builder)
+	if err != nil {
+		return err
+	}
+
+	query.Result = alerts
+	return nil
+}
+
+func GetOldestFiringAlert(query *models.GetOldestFiringAlertQuery) error {
+	builder := SqlBuilder{}
+	builder.Write(alertListItemSelect)
+	builder.Write(`WHERE alert.org_id = ? AND alert.state = ?`, query.OrgId, models.AlertStateAlerting)
+	builder.Write(` ORDER BY alert.new_state_date ASC, alert.id ASC`)
+	builder.Write(dialect.Limit(1))
+
+	alerts, err := findAlertListItems(&builder)
+	if err != nil {
+		return err
+	}
+
+	query.Result = nil
+	query.FiringDuration = 0
+	if len(alerts) > 0 {
+		query.Result = alerts[0]
+		query.FiringDuration = timeNow().Sub(alerts[0].NewStateDate)
+	}
+
+	return nil
+}
+
+// dateExpr returns an expression for the date part of a datetime, as seen
+// in the timezone of the database server.
+func dateExpr(expr string) string {
+	if dialect.DriverName() == migrator.POSTGRES {
+		return "CAST(" + expr + " AS DATE)"
+	}
+	return "DATE(" + expr + ")"
+}
+
+func GetAlertsByCreatedAt(query *models.GetAlertsByCreatedAtQuery) error {
+	builder := SqlBuilder{}
+	builder.Write(alertListItemSelect)
+	builder.Write(`WHERE alert.org_id = ? AND `+dateExpr("alert.created")+` = `+dateExpr("?"), query.OrgId, query.Date.Format("2006-01-02"))
+	builder.Write(" ORDER BY name ASC")
+
+	alerts, err := findAlertListItems(&builder)
+	if err != nil {
+		return err
+	}
+
+	query.Result = alerts
+	return nil
+}
+
+func GetAlertsCreatedRecently(query *models.GetAlertsCreatedRecentlyQuery) error {
+	builder := SqlBuilder{}
+	builder.Write(alertListItemSelect)
+	builder.Write(`WHERE alert.org_id = ? AND alert.created >= ?`, query.OrgId, timeNow().Add(-query.Since))
+	builder.Write(" ORDER BY alert.created DESC, alert.id DESC")
+
+	alerts, err := findAlertListItems(&builder)
+	if err != nil {
+		return err
+	}
+
+	query.Result = alerts
+	return nil
+}
+
+// GetUnmonitoredServices groups the alerts by the value of a tag key and
+// returns the values for which every alert is paused.
+func GetUnmonitoredServices(query *models.GetUnmonitoredServicesQuery) error {
+	rawSql := `SELECT
+		tag.` + dialect.Quote("value") + ` AS service,
+		COUNT(DISTINCT alert.id) AS alert_count,
+		COUNT(DISTINCT CASE WHEN alert.state = ? THEN alert.id END) AS paused_count
+		FROM alert
+		INNER JOIN alert_rule_tag ON alert_rule_tag.alert_id = alert.id
+		INNER JOIN tag ON tag.id = alert_rule_tag.tag_id
+		WHERE alert.org_id = ? AND tag.` + dialect.Quote("key") + ` = ?
+		GROUP BY tag.` + dialect.Quote("value") + `
+		ORDER BY service ASC`
+
+	type serviceAlerts struct {
+		Service     string
+		AlertCount  int64
+		PausedCount int64
+	}
+
+	services := make([]*serviceAlerts, 0)
+	if err := x.SQL(rawSql, models.AlertStatePaused, query.OrgId, query.TagKey).Find(&services); err != nil {
+		return err
+	}
+
+	query.Result = make([]*models.UnmonitoredServiceDTO, 0)
+	for _, service := range services {
+		if service.AlertCount > 0 && service.PausedCount == service.AlertCount {
+			query.Result = append(query.Result, &models.UnmonitoredServiceDTO{
+				Service:    service.Service,
+				AlertCount: service.AlertCount,
+			})
+		}
+	}
+
+	return nil
+}
+
+// GetAlertsByForDurationDistribution counts the alerts per distinct For
+// duration in the database and accumulates the counts into the buckets.
+func GetAlertsByForDurationDistribution(query *models.GetAlertsByForDurationDistributionQuery) error {
+	rawSql := `SELECT alert.` + dialect.Quote("for") + ` AS for_duration, COUNT(*) AS count
+		FROM alert
+		WHERE alert.org_id = ?
+		GROUP BY alert.` + dialect.Quote("for")
+
+	type forDurationCount struct {
+		ForDuration int64
+		Count       int64
+	}
+
+	counts := make([]*forDurationCount, 0)
+	if err := x.SQL(rawSql, query.OrgId).Find(&counts); err != nil {
+		return err
+	}
+
+	bounds := make([]time.Duration, len(query.Buckets))
+	copy(bounds, query.Buckets)
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+
+	query.Result = make([]*models.ForDurationBucket, 0, len(bounds))
+	for _, bound := range bounds {
+		bucket := &models.ForDurationBucket{UpperBound: bound}
+		for _, c := range counts {
+			if time.Duration(c.ForDuration) <= bound {
+				bucket.AlertCount += c.Count
+			}
+		}
+		query.Result = append(query.Result, bucket)
+	}
+
+	return nil
+}
+
+func deleteAlertDefinition(dashboardId int64, sess *DBSession) error {
+	alerts := make([]*models.Alert, 0)
+	if err := sess.Where("dashboard_id = ?", dashboardId).Find(&alerts); err != nil {
+		return err
+	}
+
+	for _, alert := range alerts {
+		if err := deleteAlertByIdInternal(alert.Id, "Dashboard deleted", sess); err != nil {
+			// If we return an error, the current transaction gets rolled back, so no use
+			// trying to delete more
+			return err
+		}
+	}
+
+	return nil
+}
+
+func SaveAlerts(cmd *models.SaveAlertsCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		existingAlerts, err := GetAlertsByDashboardId2(cmd.DashboardId, sess)
+		if err != nil {
+			return err
+		}
+
+		if err := updateAlerts(existingAlerts, cmd, sess); err != nil {
+			return err
+		}
+
+		if err := deleteMissingAlerts(existingAlerts, cmd, sess); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func updateAlerts(existingAlerts []*models.Alert, cmd *models.SaveAlertsCommand, sess *DBSession) error {
+	for _, alert := range cmd.Alerts {
+		update := false
+		var alertToUpdate *models.Alert
+
+		for _, k := range existingAlerts {
+			if alert.PanelId == k.PanelId {
+				update = true
+				alert.Id = k.Id
+				alertToUpdate = k
+				break
+			}
+		}
+
+		if update {
+			if alertToUpdate.ContainsUpdates(alert) {
+				alert.Updated = timeNow()
+				alert.State = alertToUpdate.State
+				sess.MustCols("message", "for", "eval_timeout")
+
+				_, err := sess.ID(alert.Id).Update(alert)
+				if err != nil {
+					return err
+				}
+
+				sqlog.Debug("Alert updated", "name", alert.Name, "id", alert.Id)
+			}
+		} else {
+			alert.Updated = timeNow()
+			alert.Created = timeNow()
+			if !cmd.PreserveState || !alert.State.IsValid() {
+				alert.State = models.AlertStateUnknown
+			}
+			alert.NewStateDate = timeNow()
+
+			_, err := sess.Insert(alert)
+			if err != nil {
+				return err
+			}
+
+			sqlog.Debug("Alert inserted", "name", alert.Name, "id", alert.Id)
+		}
+		if err := updateAlertRuleTags(alert, sess); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateAlertRuleTags replaces the alert_rule_tag rows of an alert with the
+// tags in its settings.
+func updateAlertRuleTags(alert *models.Alert, sess *DBSession) error {
+	tags := alert.GetTagsFromSettings()
+	if _, err := sess.Exec("DELETE FROM alert_rule_tag WHERE alert_id = ?", alert.Id); err != nil {
+		return err
+	}
+	if tags != nil {
+		tags, err := EnsureTagsExist(sess, tags)
+		if err != nil {
+			return err
+		}
+		for _, tag := range tags {
+			if _, err := sess.Exec("INSERT INTO alert_rule_tag (alert_id, tag_id) VALUES(?,?)", alert.Id, tag.Id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// updateDashboardAlertSettings copies the setting key of each alert to the
+// alert json of its panel. Alerts are extracted from the dashboard json
+// again on every dashboard save, so a setting changed on the alert only
+// would be reverted by the next save. Changed dashboards are saved as a new
+// version.
+func updateDashboardAlertSettings(alerts []*models.Alert, key string, sess *DBSession) error {
+	dashboardIds := make([]int64, 0)
+	alertsByDashboard := make(map[int64][]*models.Alert)
+	for _, alert := range alerts {
+		if _, ok := alertsByDashboard[alert.DashboardId]; !ok {
+			dashboardIds = append(dashboardIds, alert.DashboardId)
+		}
+		alertsByDashboard[alert.DashboardId] = append(alertsByDashboard[alert.DashboardId], alert)
+	}
+
+	for _, dashboardId := range dashboardIds {
+		dash := &models.Dashboard{}
+		has, err := sess.ID(dashboardId).Get(dash)
+		if err != nil {
+			return err
+		}
+		if !has || dash.Data == nil {
+			continue
+		}
+
+		panelAlerts := getDashboardPanelAlerts(dash.Data)
+		updated := false
+		for _, alert := range alertsByDashboard[dashboardId] {
+			if jsonAlert, ok := panelAlerts[alert.PanelId]; ok {
+				jsonAlert.Set(key, alert.Settings.Get(key).Interface())
+				updated = true
+			}
+		}
+		if !updated {
+			continue
+		}
+
+		parentVersion := dash.Version
+		dash.SetVersion(dash.Version + 1)
+		dash.Updated = timeNow()
+		dash.UpdatedBy = -1
+
+		if _, err := sess.ID(dash.Id).Cols("data", "version", "updated", "updated_by").Update(dash); err != nil {
+			return err
+		}
+
+		dashVersion := &models.DashboardVersion{
+			DashboardId:   dash.Id,
+			ParentVersion: parentVersion,
+			Version:       dash.Version,
+			Created:       timeNow(),
+			CreatedBy:     dash.UpdatedBy,
+			Message:       "Updated alert " + key,
+			Data:          dash.Data,
+		}
+		if _, err := sess.Insert(dashVersion); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getDashboardPanelAlerts returns the alert json of the panels of a
+// dashboard by panel id, including the panels of rows and collapsed rows.
+// The returned json shares its data with the dashboard json.
+func getDashboardPanelAlerts(data *simplejson.Json) map[int64]*simplejson.Json {
+	panelAlerts := make(map[int64]*simplejson.Json)
+
+	var collect func(jsonWithPanels *simplejson.Json)
+	collect = func(jsonWithPanels *simplejson.Json) {
+		for _, panelObj := range jsonWithPanels.Get("panels").MustArray() {
+			panel := simplejson.NewFromAny(panelObj)
+			collect(panel)
+
+			jsonAlert, hasAlert := panel.CheckGet("alert")
+			if !hasAlert {
+				continue
+			}
+			if panelId, err := panel.Get("id").Int64(); err == nil {
+				panelAlerts[panelId] = jsonAlert
+			}
+		}
+	}
+
+	for _, rowObj := range data.Get("rows").MustArray() {
+		collect(simplejson.NewFromAny(rowObj))
+	}
+	collect(data)
+
+	return panelAlerts
+}
+
+func deleteMissingAlerts(alerts []*models.Alert, cmd *models.SaveAlertsCommand, sess *DBSession) error {
+	for _, missingAlert := range alerts {
+		missing := true
+
+		for _, k := range cmd.Alerts {
+			if missingAlert.PanelId == k.PanelId {
+				missing = false
+				break
+			}
+		}
+
+		if missing {
+			if err := deleteAlertByIdInternal(missingAlert.Id, "Removed from dashboard", sess); err != nil {
+				// No use trying to delete more, since we're in a transaction and it will be
+				// rolled back on error.
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func GetAlertsByDashboardId2(dashboardId int64, sess *DBSession) ([]*models.Alert, error) {
+	alerts := make([]*models.Alert, 0)
+	err := sess.Where("dashboard_id = ?", dashboardId).Find(&alerts)
+
+	if err != nil {
+		return []*models.Alert{}, err
+	}
+
+	return alerts, nil
+}
+
+func SetAlertState(cmd *models.SetAlertStateCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		alert := models.Alert{}
+
+		if has, err := sess.ID(cmd.AlertId).Get(&alert); err != nil {
+			return err
+		} else if !has {
+			return fmt.Errorf("Could not find alert")
+		}
+
+		if alert.State == models.AlertStatePaused {
+			return models.ErrCannotChangeStateOnPausedAlert
+		}
+
+		if alert.State == cmd.State {
+			return models.ErrRequiresNewState
+		}
 
 		alert.State = cmd.State
 		alert.StateChanges++
@@ -318,50 +2977,378 @@ func SetAlertState(cmd *models.SetAlertStateCommand) error {
 	})
 }
 
-func PauseAlert(cmd *models.PauseAlertCommand) error {
-	return inTransaction(func(sess *DBSession) error {
-		if len(cmd.AlertIds) == 0 {
-			return fmt.Errorf("command contains no alertids")
+func SetAlertFrequencyByTier(cmd *models.SetAlertFrequencyByTierCommand) error {
+	for _, tier := range cmd.Tiers {
+		if tier.Frequency <= 0 {
+			return models.ErrInvalidAlertFrequency
 		}
+	}
 
-		var buffer bytes.Buffer
-		params := make([]interface{}, 0)
+	return inTransaction(func(sess *DBSession) error {
+		alerts, err := getAlertsByOrgId(cmd.OrgId, sess)
+		if err != nil {
+			return err
+		}
 
-		buffer.WriteString(`UPDATE alert SET state = ?, new_state_date = ?`)
-		if cmd.Paused {
-			params = append(params, string(models.AlertStatePaused))
-			params = append(params, timeNow().UTC())
-		} else {
-			params = append(params, string(models.AlertStateUnknown))
-			params = append(params, timeNow().UTC())
+		tierAlerts := make([][]*models.Alert, len(cmd.Tiers))
+		for _, alert := range alerts {
+			for i, tier := range cmd.Tiers {
+				if tier.Filter.Matches(alert) {
+					tierAlerts[i] = append(tierAlerts[i], alert)
+					break
+				}
+			}
 		}
 
-		buffer.WriteString(` WHERE id IN (?` + strings.Repeat(",?", len(cmd.AlertIds)-1) + `)`)
-		for _, v := range cmd.AlertIds {
-			params = append(params, v)
+		cmd.Result = make([]int64, len(cmd.Tiers))
+		updated := make([]*models.Alert, 0)
+		for i, matched := range tierAlerts {
+			for _, alert := range matched {
+				// the frequency column is extracted from the frequency
+				// setting, both have to change for the change to last
+				alert.Frequency = cmd.Tiers[i].Frequency
+				if alert.Settings == nil {
+					alert.Settings = simplejson.New()
+				}
+				alert.Settings.Set("frequency", fmt.Sprintf("%ds", alert.Frequency))
+				alert.Updated = timeNow()
+
+				if _, err := sess.ID(alert.Id).Cols("frequency", "settings", "updated").Update(alert); err != nil {
+					return err
+				}
+				updated = append(updated, alert)
+			}
+
+			cmd.Result[i] = int64(len(matched))
 		}
 
-		sqlOrArgs := append([]interface{}{buffer.String()}, params...)
+		return updateDashboardAlertSettings(updated, "frequency", sess)
+	})
+}
 
-		res, err := sess.Exec(sqlOrArgs...)
+func SilenceAlertsByFilter(cmd *models.SilenceAlertsByFilterCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		ids, err := getAlertIdsByFilter(cmd.OrgId, cmd.Filter, sess)
 		if err != nil {
 			return err
 		}
-		cmd.ResultCount, _ = res.RowsAffected()
+
+		if len(ids) == 0 {
+			cmd.ResultCount = 0
+			return nil
+		}
+
+		builder := SqlBuilder{}
+		builder.Write(`UPDATE alert SET silenced = ?`, cmd.Silenced)
+		builder.Write(` WHERE id IN (?` + strings.Repeat(",?", len(ids)-1) + `)`)
+		for _, id := range ids {
+			builder.AddParams(id)
+		}
+
+		sqlOrArgs := append([]interface{}{builder.GetSqlString()}, builder.params...)
+		if _, err := sess.Exec(sqlOrArgs...); err != nil {
+			return err
+		}
+
+		cmd.ResultCount = int64(len(ids))
 		return nil
 	})
 }
 
+func SetExecutionErrorState(cmd *models.SetExecutionErrorStateCommand) error {
+	if !cmd.ExecutionErrorState.IsValid() {
+		return models.ErrInvalidExecutionErrorState
+	}
+
+	return inTransaction(func(sess *DBSession) error {
+		ids, err := getAlertIdsByFilter(cmd.OrgId, cmd.Filter, sess)
+		if err != nil {
+			return err
+		}
+
+		cmd.ResultCount = 0
+		if len(ids) == 0 {
+			return nil
+		}
+
+		alerts := make([]*models.Alert, 0)
+		if err := sess.In("id", ids).Find(&alerts); err != nil {
+			return err
+		}
+
+		for _, alert := range alerts {
+			if alert.Settings == nil {
+				alert.Settings = simplejson.New()
+			}
+			alert.Settings.Set("executionErrorState", string(cmd.ExecutionErrorState))
+			alert.Updated = timeNow()
+
+			if _, err := sess.ID(alert.Id).Cols("settings", "updated").Update(alert); err != nil {
+				return err
+			}
+		}
+
+		cmd.ResultCount = int64(len(alerts))
+		return updateDashboardAlertSettings(alerts, "executionErrorState", sess)
+	})
+}
+
+// PurgeDanglingAlertNotifications rewrites the notifications setting of the
+// alerts referencing deleted notification channels, and of their panels, so
+// that only the references to existing channels remain.
+func PurgeDanglingAlertNotifications(cmd *models.PurgeDanglingAlertNotificationsCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		alerts, err := getAlertsByOrgId(cmd.OrgId, sess)
+		if err != nil {
+			return err
+		}
+
+		notifications, err := getAlertNotificationsByOrgId(cmd.OrgId, sess)
+		if err != nil {
+			return err
+		}
+
+		purged := make([]*models.Alert, 0)
+		for _, alert := range alerts {
+			resolved, dangling := partitionNotificationRefs(alert, notifications)
+			if len(dangling) == 0 {
+				continue
+			}
+
+			alert.Settings.Set("notifications", notificationRefsToSettings(resolved))
+			alert.Updated = timeNow()
+
+			if _, err := sess.ID(alert.Id).Cols("settings", "updated").Update(alert); err != nil {
+				return err
+			}
+			purged = append(purged, alert)
+		}
+
+		cmd.ResultCount = int64(len(purged))
+		return updateDashboardAlertSettings(purged, "notifications", sess)
+	})
+}
+
+// ImportAlertTags replaces the tags of each alert in the mapping, in the
+// alertRuleTags setting of the alert and of its panel and in alert_rule_tag.
+// Alerts that do not exist in the org are reported and skipped.
+func ImportAlertTags(cmd *models.ImportAlertTagsCommand) error {
+	alertIds := make([]int64, 0, len(cmd.Tags))
+	for alertId := range cmd.Tags {
+		alertIds = append(alertIds, alertId)
+	}
+	sort.Slice(alertIds, func(i, j int) bool { return alertIds[i] < alertIds[j] })
+
+	return inTransaction(func(sess *DBSession) error {
+		cmd.Result = make([]*models.ImportAlertTagsResult, 0, len(alertIds))
+
+		imported := make([]*models.Alert, 0, len(alertIds))
+		for _, alertId := range alertIds {
+			result := &models.ImportAlertTagsResult{AlertId: alertId}
+			cmd.Result = append(cmd.Result, result)
+
+			alert := &models.Alert{}
+			has, err := sess.Where("id = ? AND org_id = ?", alertId, cmd.OrgId).Get(alert)
+			if err != nil {
+				return err
+			}
+			if !has {
+				result.Error = models.ErrAlertNotFound.Error()
+				continue
+			}
+
+			// settings hold one value per key, the last tag of a key wins
+			ruleTags := make(map[string]interface{})
+			for _, tag := range cmd.Tags[alertId] {
+				ruleTags[tag.Key] = tag.Value
+			}
+
+			if alert.Settings == nil {
+				alert.Settings = simplejson.New()
+			}
+			alert.Settings.Set("alertRuleTags", ruleTags)
+			alert.Updated = timeNow()
+
+			if _, err := sess.ID(alert.Id).Cols("settings", "updated").Update(alert); err != nil {
+				return err
+			}
+			if err := updateAlertRuleTags(alert, sess); err != nil {
+				return err
+			}
+
+			result.TagCount = len(ruleTags)
+			imported = append(imported, alert)
+		}
+
+		return updateDashboardAlertSettings(imported, "alertRuleTags", sess)
+	})
+}
+
+// RenameAlertTagValue replaces the value of a tag on the alerts of an org,
+// in their settings, their panels and in alert_rule_tag. Tag rows are shared with other
+// orgs and with annotations, so they are never renamed: the alerts are
+// linked to the tag with the new value instead, which is created if needed.
+func RenameAlertTagValue(cmd *models.RenameAlertTagValueCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		rawSql := `SELECT alert.*
+			FROM alert
+			INNER JOIN alert_rule_tag ON alert_rule_tag.alert_id = alert.id
+			INNER JOIN tag ON tag.id = alert_rule_tag.tag_id
+			WHERE alert.org_id = ? AND tag.` + dialect.Quote("key") + ` = ? AND tag.` + dialect.Quote("value") + ` = ?`
+
+		alerts := make([]*models.Alert, 0)
+		if err := sess.SQL(rawSql, cmd.OrgId, cmd.Key, cmd.OldValue).Find(&alerts); err != nil {
+			return err
+		}
+
+		for _, alert := range alerts {
+			if alert.Settings == nil {
+				alert.Settings = simplejson.New()
+			}
+			alert.Settings.SetPath([]string{"alertRuleTags", cmd.Key}, cmd.NewValue)
+			alert.Updated = timeNow()
+
+			if _, err := sess.ID(alert.Id).Cols("settings", "updated").Update(alert); err != nil {
+				return err
+			}
+			if err := updateAlertRuleTags(alert, sess); err != nil {
+				return err
+			}
+		}
+
+		cmd.ResultCount = int64(len(alerts))
+		return updateDashboardAlertSettings(alerts, "alertRuleTags", sess)
+	})
+}
+
+// ReplaceNotificationChannel makes the alerts sending to the old channel send
+// to the new one instead, optionally only the alerts matching Filter, in the
+// alerts and in their panels. The old channel may already be deleted, then
+// only references by uid are found.
+func ReplaceNotificationChannel(cmd *models.ReplaceNotificationChannelCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		newQuery := &models.GetAlertNotificationsWithUidQuery{OrgId: cmd.OrgId, Uid: cmd.NewUid}
+		if err := getAlertNotificationWithUidInternal(newQuery, sess); err != nil {
+			return err
+		}
+		if newQuery.Result == nil {
+			return models.ErrAlertNotificationNotFound
+		}
+		newNotification := newQuery.Result
+
+		oldQuery := &models.GetAlertNotificationsWithUidQuery{OrgId: cmd.OrgId, Uid: cmd.OldUid}
+		if err := getAlertNotificationWithUidInternal(oldQuery, sess); err != nil {
+			return err
+		}
+		oldNotification := oldQuery.Result
+		if oldNotification == nil {
+			oldNotification = &models.AlertNotification{OrgId: cmd.OrgId, Uid: cmd.OldUid}
+		}
+
+		alerts, err := getAlertsReferencingNotification(oldNotification, sess)
+		if err != nil {
+			return err
+		}
+
+		var filtered map[int64]bool
+		if cmd.Filter != nil {
+			ids, err := getAlertIdsByFilter(cmd.OrgId, cmd.Filter, sess)
+			if err != nil {
+				return err
+			}
+			filtered = make(map[int64]bool, len(ids))
+			for _, id := range ids {
+				filtered[id] = true
+			}
+		}
+
+		replaced := make([]*models.Alert, 0)
+		for _, alert := range alerts {
+			if filtered != nil && !filtered[alert.Id] {
+				continue
+			}
+
+			refs := make([]*models.AlertNotificationRef, 0)
+			hasNew := false
+			for _, ref := range alert.GetNotificationsFromSettings() {
+				if ref.Matches(oldNotification) || ref.Matches(newNotification) {
+					if !hasNew {
+						refs = append(refs, &models.AlertNotificationRef{Uid: newNotification.Uid})
+						hasNew = true
+					}
+					continue
+				}
+				refs = append(refs, ref)
+			}
+
+			alert.Settings.Set("notifications", notificationRefsToSettings(refs))
+			alert.Updated = timeNow()
+
+			if _, err := sess.ID(alert.Id).Cols("settings", "updated").Update(alert); err != nil {
+				return err
+			}
+			replaced = append(replaced, alert)
+		}
+
+		cmd.ResultCount = int64(len(replaced))
+		return updateDashboardAlertSettings(replaced, "notifications", sess)
+	})
+}
+
+func PauseAlert(cmd *models.PauseAlertCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		return pauseAlerts(cmd, sess)
+	})
+}
+
+func pauseAlerts(cmd *models.PauseAlertCommand, sess *DBSession) error {
+	if len(cmd.AlertIds) == 0 {
+		return fmt.Errorf("command contains no alertids")
+	}
+
+	var buffer bytes.Buffer
+	params := make([]interface{}, 0)
+
+	buffer.WriteString(`UPDATE alert SET state = ?, new_state_date = ?, pause_reason = ?, paused_by = ?`)
+	if cmd.Paused {
+		params = append(params, string(models.AlertStatePaused))
+		params = append(params, timeNow().UTC())
+		params = append(params, cmd.PauseReason, cmd.PausedBy)
+	} else {
+		params = append(params, string(models.AlertStateUnknown))
+		params = append(params, timeNow().UTC())
+		params = append(params, "", 0)
+	}
+
+	buffer.WriteString(` WHERE id IN (?` + strings.Repeat(",?", len(cmd.AlertIds)-1) + `)`)
+	for _, v := range cmd.AlertIds {
+		params = append(params, v)
+	}
+
+	sqlOrArgs := append([]interface{}{buffer.String()}, params...)
+
+	res, err := sess.Exec(sqlOrArgs...)
+	if err != nil {
+		return err
+	}
+	cmd.ResultCount, _ = res.RowsAffected()
+	return nil
+}
+
 func PauseAllAlerts(cmd *models.PauseAllAlertCommand) error {
 	return inTransaction(func(sess *DBSession) error {
 		var newState string
+		var pauseReason string
+		var pausedBy int64
 		if cmd.Paused {
 			newState = string(models.AlertStatePaused)
+			pauseReason = cmd.PauseReason
+			pausedBy = cmd.PausedBy
 		} else {
 			newState = string(models.AlertStateUnknown)
 		}
 
-		res, err := sess.Exec(`UPDATE alert SET state = ?, new_state_date = ?`, newState, timeNow().UTC())
+		res, err := sess.Exec(`UPDATE alert SET state = ?, new_state_date = ?, pause_reason = ?, paused_by = ?`, newState, timeNow().UTC(), pauseReason, pausedBy)
 		if err != nil {
 			return err
 		}
@@ -385,3 +3372,87 @@ func GetAlertStatesForDashboard(query *models.GetAlertStatesForDashboardQuery) e
 
 	return err
 }
+
+func GetAlertsByDashboardAndState(query *models.GetAlertsByDashboardAndStateQuery) error {
+	builder := SqlBuilder{}
+	builder.Write(`SELECT
+	                id,
+	                dashboard_id,
+	                panel_id,
+	                state,
+	                new_state_date
+	                FROM alert
+	                WHERE org_id = ? AND dashboard_id = ?`, query.OrgId, query.DashboardId)
+
+	if len(query.State) > 0 {
+		builder.Write(` AND state IN (?` + strings.Repeat(",?", len(query.State)-1) + `)`)
+		for _, state := range query.State {
+			builder.AddParams(state)
+		}
+	}
+
+	builder.Write(` ORDER BY panel_id ASC`)
+
+	query.Result = make([]*models.AlertStateInfoDTO, 0)
+	return x.SQL(builder.GetSqlString(), builder.params...).Find(&query.Result)
+}
+
+func GetAlertCountByDashboard(query *models.GetAlertCountByDashboardQuery) error {
+	query.Result = make(map[int64]map[models.AlertStateType]int64)
+	if len(query.DashboardIds) == 0 {
+		return nil
+	}
+
+	builder := SqlBuilder{}
+	builder.Write(`SELECT dashboard_id, state, COUNT(*) AS count
+		FROM alert
+		WHERE org_id = ?`, query.OrgId)
+	builder.Write(` AND dashboard_id IN (?` + strings.Repeat(",?", len(query.DashboardIds)-1) + `)`)
+	for _, dashboardId := range query.DashboardIds {
+		builder.AddParams(dashboardId)
+	}
+	builder.Write(` GROUP BY dashboard_id, state`)
+
+	type dashboardStateCount struct {
+		DashboardId int64
+		State       models.AlertStateType
+		Count       int64
+	}
+
+	counts := make([]*dashboardStateCount, 0)
+	if err := x.SQL(builder.GetSqlString(), builder.params...).Find(&counts); err != nil {
+		return err
+	}
+
+	for _, c := range counts {
+		if query.Result[c.DashboardId] == nil {
+			query.Result[c.DashboardId] = make(map[models.AlertStateType]int64)
+		}
+		query.Result[c.DashboardId][c.State] = c.Count
+	}
+
+	return nil
+}
+
+func GetAlertStatesForPanels(query *models.GetAlertStatesForPanelsQuery) error {
+	query.Result = make([]*models.AlertStateInfoDTO, 0)
+	if len(query.PanelIds) == 0 {
+		return nil
+	}
+
+	builder := SqlBuilder{}
+	builder.Write(`SELECT
+	                id,
+	                dashboard_id,
+	                panel_id,
+	                state,
+	                new_state_date
+	                FROM alert
+	                WHERE org_id = ?`, query.OrgId)
+	builder.Write(` AND panel_id IN (?` + strings.Repeat(",?", len(query.PanelIds)-1) + `)`)
+	for _, panelId := range query.PanelIds {
+		builder.AddParams(panelId)
+	}
+
+	return x.SQL(builder.GetSqlString(), builder.params...).Find(&query.Result)
+}
@@ -0,0 +1,312 @@
+package sqlstore
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+func init() {
+	bus.AddHandler("sql", CreateAlertMaintenanceWindow)
+	bus.AddHandler("sql", CancelAlertMaintenanceWindow)
+	bus.AddHandler("sql", ApplyAlertMaintenanceWindows)
+}
+
+func CreateAlertMaintenanceWindow(cmd *models.CreateAlertMaintenanceWindowCommand) error {
+	if !cmd.Ends.After(cmd.Starts) {
+		return models.ErrAlertMaintenanceWindowInvalidRange
+	}
+
+	return inTransaction(func(sess *DBSession) error {
+		alertIds, err := getMaintenanceWindowAlertIds(cmd, sess)
+		if err != nil {
+			return err
+		}
+		if len(alertIds) == 0 {
+			return models.ErrAlertMaintenanceWindowNoAlerts
+		}
+
+		window := &models.AlertMaintenanceWindow{
+			OrgId:     cmd.OrgId,
+			Starts:    cmd.Starts,
+			Ends:      cmd.Ends,
+			State:     models.AlertMaintenanceWindowScheduled,
+			CreatedBy: cmd.UserId,
+			Created:   timeNow(),
+			Updated:   timeNow(),
+		}
+
+		if _, err := sess.Insert(window); err != nil {
+			return err
+		}
+
+		for _, alertId := range alertIds {
+			if _, err := sess.Insert(&models.AlertMaintenanceWindowAlert{WindowId: window.Id, AlertId: alertId}); err != nil {
+				return err
+			}
+		}
+
+		cmd.Result = window
+		return nil
+	})
+}
+
+// getMaintenanceWindowAlertIds returns the ids of the alerts of the org that
+// are either listed by id or carry all of the tags of the command.
+func getMaintenanceWindowAlertIds(cmd *models.CreateAlertMaintenanceWindowCommand, sess *DBSession) ([]int64, error) {
+	ids := make(map[int64]bool)
+
+	if len(cmd.AlertIds) > 0 {
+		builder := SqlBuilder{}
+		builder.Write(`SELECT id FROM alert WHERE org_id = ?`, cmd.OrgId)
+		builder.Write(` AND id IN (?` + strings.Repeat(",?", len(cmd.AlertIds)-1) + `)`)
+		for _, id := range cmd.AlertIds {
+			builder.AddParams(id)
+		}
+
+		alerts := []struct {
+			Id int64
+		}{}
+		if err := sess.SQL(builder.GetSqlString(), builder.params...).Find(&alerts); err != nil {
+			return nil, err
+		}
+		for _, alert := range alerts {
+			ids[alert.Id] = true
+		}
+	}
+
+	if len(cmd.Tags) > 0 {
+		tagged, err := getAlertIdsByFilter(cmd.OrgId, &models.GetAlertsQuery{OrgId: cmd.OrgId, Tags: cmd.Tags}, sess)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range tagged {
+			ids[id] = true
+		}
+	}
+
+	result := make([]int64, 0, len(ids))
+	for id := range ids {
+		result = append(result, id)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+
+	return result, nil
+}
+
+// CancelAlertMaintenanceWindow leaves Result nil for windows that have
+// already ended or been cancelled.
+func CancelAlertMaintenanceWindow(cmd *models.CancelAlertMaintenanceWindowCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		window := &models.AlertMaintenanceWindow{}
+		has, err := sess.Where("id = ? AND org_id = ?", cmd.Id, cmd.OrgId).Get(window)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return models.ErrAlertMaintenanceWindowNotFound
+		}
+
+		cmd.Result = nil
+		if window.State != models.AlertMaintenanceWindowScheduled && window.State != models.AlertMaintenanceWindowActive {
+			return nil
+		}
+
+		var resumed int64
+		if window.State == models.AlertMaintenanceWindowActive {
+			if resumed, err = endMaintenanceWindow(window, sess); err != nil {
+				return err
+			}
+		}
+
+		if err := setMaintenanceWindowState(window, models.AlertMaintenanceWindowCancelled, sess); err != nil {
+			return err
+		}
+
+		cmd.Result = &models.AlertMaintenanceWindowTransition{
+			WindowId:   window.Id,
+			OrgId:      window.OrgId,
+			State:      window.State,
+			AlertCount: resumed,
+		}
+		return nil
+	})
+}
+
+// ApplyAlertMaintenanceWindows is meant to be called periodically. Windows
+// that ended before they could be started are ended without pausing any
+// alert.
+func ApplyAlertMaintenanceWindows(cmd *models.ApplyAlertMaintenanceWindowsCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		windows := make([]*models.AlertMaintenanceWindow, 0)
+		err := sess.Where("(state = ? AND starts <= ?) OR (state = ? AND ends <= ?)",
+			models.AlertMaintenanceWindowScheduled, cmd.Now,
+			models.AlertMaintenanceWindowActive, cmd.Now).
+			Asc("id").
+			Find(&windows)
+		if err != nil {
+			return err
+		}
+
+		cmd.Result = make([]*models.AlertMaintenanceWindowTransition, 0)
+		for _, window := range windows {
+			var count int64
+			newState := models.AlertMaintenanceWindowEnded
+
+			if window.Ends.After(cmd.Now) {
+				newState = models.AlertMaintenanceWindowActive
+				count, err = startMaintenanceWindow(window, sess)
+			} else if window.State == models.AlertMaintenanceWindowActive {
+				count, err = endMaintenanceWindow(window, sess)
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := setMaintenanceWindowState(window, newState, sess); err != nil {
+				return err
+			}
+
+			cmd.Result = append(cmd.Result, &models.AlertMaintenanceWindowTransition{
+				WindowId:   window.Id,
+				OrgId:      window.OrgId,
+				State:      newState,
+				AlertCount: count,
+			})
+		}
+
+		return nil
+	})
+}
+
+// startMaintenanceWindow pauses the alerts of the window that aren't paused
+// yet and remembers which ones it paused.
+func startMaintenanceWindow(window *models.AlertMaintenanceWindow, sess *DBSession) (int64, error) {
+	alertIds, err := getMaintenanceWindowAlerts(window.Id, `alert.state <> ?`, []interface{}{models.AlertStatePaused}, sess)
+	if err != nil || len(alertIds) == 0 {
+		return 0, err
+	}
+
+	cmd := &models.PauseAlertCommand{
+		OrgId:       window.OrgId,
+		AlertIds:    alertIds,
+		Paused:      true,
+		PauseReason: fmt.Sprintf("Maintenance window %d", window.Id),
+		PausedBy:    window.CreatedBy,
+	}
+	if err := pauseAlerts(cmd, sess); err != nil {
+		return 0, err
+	}
+
+	if err := setMaintenanceWindowAlertsPaused(alertIds, `window_id = ?`, []interface{}{window.Id}, sess); err != nil {
+		return 0, err
+	}
+
+	return cmd.ResultCount, nil
+}
+
+// endMaintenanceWindow resumes the alerts the window paused. Alerts that are
+// part of another active window stay paused, that window resumes them when
+// it ends.
+func endMaintenanceWindow(window *models.AlertMaintenanceWindow, sess *DBSession) (int64, error) {
+	alertIds, err := getMaintenanceWindowAlerts(window.Id, `alert_maintenance_window_alert.paused = ? AND alert.state = ?`, []interface{}{true, models.AlertStatePaused}, sess)
+	if err != nil || len(alertIds) == 0 {
+		return 0, err
+	}
+
+	builder := SqlBuilder{}
+	builder.Write(`SELECT DISTINCT alert_maintenance_window_alert.alert_id AS id
+		FROM alert_maintenance_window_alert
+		INNER JOIN alert_maintenance_window ON alert_maintenance_window.id = alert_maintenance_window_alert.window_id
+		WHERE alert_maintenance_window.state = ? AND alert_maintenance_window.id <> ?`, models.AlertMaintenanceWindowActive, window.Id)
+	builder.Write(` AND alert_maintenance_window_alert.alert_id IN (?` + strings.Repeat(",?", len(alertIds)-1) + `)`)
+	for _, id := range alertIds {
+		builder.AddParams(id)
+	}
+
+	stillInMaintenance := []struct {
+		Id int64
+	}{}
+	if err := sess.SQL(builder.GetSqlString(), builder.params...).Find(&stillInMaintenance); err != nil {
+		return 0, err
+	}
+
+	handedOver := make(map[int64]bool)
+	handedOverIds := make([]int64, 0)
+	for _, alert := range stillInMaintenance {
+		handedOver[alert.Id] = true
+		handedOverIds = append(handedOverIds, alert.Id)
+	}
+
+	if len(handedOverIds) > 0 {
+		activeWindows := `window_id IN (SELECT id FROM alert_maintenance_window WHERE state = ? AND id <> ?)`
+		if err := setMaintenanceWindowAlertsPaused(handedOverIds, activeWindows, []interface{}{models.AlertMaintenanceWindowActive, window.Id}, sess); err != nil {
+			return 0, err
+		}
+	}
+
+	resumeIds := make([]int64, 0)
+	for _, id := range alertIds {
+		if !handedOver[id] {
+			resumeIds = append(resumeIds, id)
+		}
+	}
+	if len(resumeIds) == 0 {
+		return 0, nil
+	}
+
+	cmd := &models.PauseAlertCommand{OrgId: window.OrgId, AlertIds: resumeIds, Paused: false}
+	if err := pauseAlerts(cmd, sess); err != nil {
+		return 0, err
+	}
+
+	return cmd.ResultCount, nil
+}
+
+// getMaintenanceWindowAlerts returns the ids of the alerts of a window that
+// match the condition on alert and alert_maintenance_window_alert.
+func getMaintenanceWindowAlerts(windowId int64, condition string, params []interface{}, sess *DBSession) ([]int64, error) {
+	builder := SqlBuilder{}
+	builder.Write(`SELECT alert.id
+		FROM alert_maintenance_window_alert
+		INNER JOIN alert ON alert.id = alert_maintenance_window_alert.alert_id
+		WHERE alert_maintenance_window_alert.window_id = ?`, windowId)
+	builder.Write(` AND `+condition, params...)
+	builder.Write(` ORDER BY alert.id ASC`)
+
+	alerts := []struct {
+		Id int64
+	}{}
+	if err := sess.SQL(builder.GetSqlString(), builder.params...).Find(&alerts); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(alerts))
+	for _, alert := range alerts {
+		ids = append(ids, alert.Id)
+	}
+	return ids, nil
+}
+
+func setMaintenanceWindowAlertsPaused(alertIds []int64, condition string, params []interface{}, sess *DBSession) error {
+	builder := SqlBuilder{}
+	builder.Write(`UPDATE alert_maintenance_window_alert SET paused = ? WHERE `+condition, append([]interface{}{true}, params...)...)
+	builder.Write(` AND alert_id IN (?` + strings.Repeat(",?", len(alertIds)-1) + `)`)
+	for _, id := range alertIds {
+		builder.AddParams(id)
+	}
+
+	_, err := sess.Exec(append([]interface{}{builder.GetSqlString()}, builder.params...)...)
+	return err
+}
+
+func setMaintenanceWindowState(window *models.AlertMaintenanceWindow, state models.AlertMaintenanceWindowState, sess *DBSession) error {
+	window.State = state
+	window.Updated = timeNow()
+
+	_, err := sess.ID(window.Id).Cols("state", "updated").Update(window)
+	return err
+}
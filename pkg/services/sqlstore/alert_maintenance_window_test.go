@@ -0,0 +1,129 @@
+package sqlstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAlertMaintenanceWindows(t *testing.T) {
+	Convey("Given alerts to maintain", t, func() {
+		InitTestDB(t)
+
+		prod, _ := simplejson.NewJson([]byte(`{"alertRuleTags": {"env": "prod"}}`))
+		first, err := insertTestAlert("first", "", 1, insertTestDashboard("first", 1, 0, false).Id, prod)
+		So(err, ShouldBeNil)
+		second, err := insertTestAlert("second", "", 1, insertTestDashboard("second", 1, 0, false).Id, prod)
+		So(err, ShouldBeNil)
+		paused, err := insertTestAlert("paused", "", 1, insertTestDashboard("paused", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		other, err := insertTestAlert("other", "", 1, insertTestDashboard("other", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+
+		_, err = pauseAlert(1, paused.Id, true)
+		So(err, ShouldBeNil)
+
+		now := time.Now()
+		create := &models.CreateAlertMaintenanceWindowCommand{
+			OrgId:    1,
+			UserId:   2,
+			Starts:   now.Add(time.Hour),
+			Ends:     now.Add(2 * time.Hour),
+			AlertIds: []int64{paused.Id},
+			Tags:     []string{"env:prod"},
+		}
+		So(CreateAlertMaintenanceWindow(create), ShouldBeNil)
+		So(create.Result.State, ShouldEqual, models.AlertMaintenanceWindowScheduled)
+
+		apply := func(at time.Time) []*models.AlertMaintenanceWindowTransition {
+			cmd := &models.ApplyAlertMaintenanceWindowsCommand{Now: at}
+			So(ApplyAlertMaintenanceWindows(cmd), ShouldBeNil)
+			return cmd.Result
+		}
+
+		Convey("Should not change alerts before the window starts", func() {
+			So(apply(now), ShouldBeEmpty)
+		})
+
+		Convey("Should pause the alerts when the window starts", func() {
+			transitions := apply(now.Add(time.Hour))
+			So(transitions, ShouldHaveLength, 1)
+			So(transitions[0].State, ShouldEqual, models.AlertMaintenanceWindowActive)
+			So(transitions[0].AlertCount, ShouldEqual, 2)
+
+			alert, err := getAlertById(first.Id)
+			So(err, ShouldBeNil)
+			So(alert.State, ShouldEqual, models.AlertStatePaused)
+			So(alert.PausedBy, ShouldEqual, 2)
+
+			alert, err = getAlertById(other.Id)
+			So(err, ShouldBeNil)
+			So(alert.State, ShouldNotEqual, models.AlertStatePaused)
+
+			Convey("Should only resume the alerts it paused when the window ends", func() {
+				transitions := apply(now.Add(2 * time.Hour))
+				So(transitions, ShouldHaveLength, 1)
+				So(transitions[0].State, ShouldEqual, models.AlertMaintenanceWindowEnded)
+				So(transitions[0].AlertCount, ShouldEqual, 2)
+
+				alert, err := getAlertById(second.Id)
+				So(err, ShouldBeNil)
+				So(alert.State, ShouldEqual, models.AlertStateUnknown)
+
+				alert, err = getAlertById(paused.Id)
+				So(err, ShouldBeNil)
+				So(alert.State, ShouldEqual, models.AlertStatePaused)
+			})
+
+			Convey("Should resume the alerts when the window is cancelled", func() {
+				cancel := &models.CancelAlertMaintenanceWindowCommand{OrgId: 1, Id: create.Result.Id}
+				So(CancelAlertMaintenanceWindow(cancel), ShouldBeNil)
+				So(cancel.Result.State, ShouldEqual, models.AlertMaintenanceWindowCancelled)
+				So(cancel.Result.AlertCount, ShouldEqual, 2)
+
+				So(apply(now.Add(2*time.Hour)), ShouldBeEmpty)
+			})
+		})
+
+		Convey("Should keep alerts paused while another window is active", func() {
+			overlapping := &models.CreateAlertMaintenanceWindowCommand{
+				OrgId:    1,
+				Starts:   now.Add(30 * time.Minute),
+				Ends:     now.Add(3 * time.Hour),
+				AlertIds: []int64{first.Id},
+			}
+			So(CreateAlertMaintenanceWindow(overlapping), ShouldBeNil)
+
+			apply(now.Add(time.Hour))
+			apply(now.Add(2 * time.Hour))
+
+			alert, err := getAlertById(first.Id)
+			So(err, ShouldBeNil)
+			So(alert.State, ShouldEqual, models.AlertStatePaused)
+
+			apply(now.Add(3 * time.Hour))
+
+			alert, err = getAlertById(first.Id)
+			So(err, ShouldBeNil)
+			So(alert.State, ShouldEqual, models.AlertStateUnknown)
+		})
+
+		Convey("Should end windows that were never started without pausing alerts", func() {
+			transitions := apply(now.Add(3 * time.Hour))
+			So(transitions, ShouldHaveLength, 1)
+			So(transitions[0].State, ShouldEqual, models.AlertMaintenanceWindowEnded)
+			So(transitions[0].AlertCount, ShouldEqual, 0)
+		})
+
+		Convey("Should reject invalid windows", func() {
+			invalid := &models.CreateAlertMaintenanceWindowCommand{OrgId: 1, Starts: now, Ends: now, AlertIds: []int64{first.Id}}
+			So(CreateAlertMaintenanceWindow(invalid), ShouldEqual, models.ErrAlertMaintenanceWindowInvalidRange)
+
+			empty := &models.CreateAlertMaintenanceWindowCommand{OrgId: 2, Starts: now, Ends: now.Add(time.Hour), AlertIds: []int64{first.Id}}
+			So(CreateAlertMaintenanceWindow(empty), ShouldEqual, models.ErrAlertMaintenanceWindowNoAlerts)
+		})
+	})
+}
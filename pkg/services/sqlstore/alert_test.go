@@ -1,11 +1,17 @@
 package sqlstore
 
 import (
+	"context"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/annotations"
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+	"github.com/grafana/grafana/pkg/setting"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -137,6 +143,76 @@ func TestAlertingDataAccess(t *testing.T) {
 			So(alertQuery.Result, ShouldHaveLength, 1)
 		})
 
+		Convey("Can filter alerts on dashboards in the General folder", func() {
+			folder := insertTestDashboard("folder", 1, 0, true)
+			folderDash := insertTestDashboard("dashboard in folder", 1, folder.Id, false)
+			_, err := insertTestAlert("Filed alert", "Alerting message", 1, folderDash.Id, simplejson.New())
+			So(err, ShouldBeNil)
+
+			query := models.GetAlertsQuery{OrgId: 1, GeneralFolderOnly: true, User: &models.SignedInUser{OrgRole: models.ROLE_ADMIN}}
+			err = HandleAlertsQuery(&query)
+			So(err, ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Name, ShouldEqual, "Alerting title")
+		})
+
+		Convey("Viewer can only list alerts at view permission level", func() {
+			viewerUser := &models.SignedInUser{OrgRole: models.ROLE_VIEWER, OrgId: 1}
+
+			query := &models.GetAlertsByDashboardPermissionQuery{OrgId: 1, User: viewerUser, PermissionLevel: models.PERMISSION_VIEW}
+			So(GetAlertsByDashboardPermission(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+
+			query = &models.GetAlertsByDashboardPermissionQuery{OrgId: 1, User: viewerUser, PermissionLevel: models.PERMISSION_EDIT}
+			So(GetAlertsByDashboardPermission(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 0)
+		})
+
+		Convey("Can filter alerts evaluating at least every n seconds", func() {
+			admin := &models.SignedInUser{OrgRole: models.ROLE_ADMIN}
+
+			query := models.GetAlertsQuery{OrgId: 1, MaxFrequency: 1, User: admin}
+			So(HandleAlertsQuery(&query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+		})
+
+		Convey("Can search alerts by message", func() {
+			admin := &models.SignedInUser{OrgRole: models.ROLE_ADMIN}
+
+			query := models.GetAlertsQuery{OrgId: 1, MessageQuery: "ing mess", User: admin}
+			So(HandleAlertsQuery(&query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+
+			query = models.GetAlertsQuery{OrgId: 1, MessageQuery: "title", User: admin}
+			So(HandleAlertsQuery(&query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 0)
+		})
+
+		Convey("Can filter alerts by dashboard slug pattern", func() {
+			admin := &models.SignedInUser{OrgRole: models.ROLE_ADMIN}
+
+			query := models.GetAlertsQuery{OrgId: 1, DashboardSlugLike: "dashboard-*-alerts", User: admin}
+			So(HandleAlertsQuery(&query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+
+			query = models.GetAlertsQuery{OrgId: 1, DashboardSlugLike: "dashboard_with*", User: admin}
+			So(HandleAlertsQuery(&query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 0)
+		})
+
+		Convey("Can find alerts evaluating more often than allowed", func() {
+			query := &models.GetAlertFrequencyViolationsQuery{OrgId: 1, MaxAllowedFrequency: 30}
+			err := GetAlertFrequencyViolations(query)
+			So(err, ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Name, ShouldEqual, "Alerting title")
+
+			query = &models.GetAlertFrequencyViolationsQuery{OrgId: 1, MaxAllowedFrequency: 1}
+			err = GetAlertFrequencyViolations(query)
+			So(err, ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 0)
+		})
+
 		Convey("Alerts with same dashboard id and panel id should update", func() {
 			modifiedItems := items
 			modifiedItems[0].Name = "Name"
@@ -303,38 +379,2596 @@ func TestPausingAlerts(t *testing.T) {
 		})
 	})
 }
-func pauseAlert(orgId int64, alertId int64, pauseState bool) (int64, error) {
-	cmd := &models.PauseAlertCommand{
-		OrgId:    orgId,
-		AlertIds: []int64{alertId},
-		Paused:   pauseState,
-	}
-	err := PauseAlert(cmd)
-	So(err, ShouldBeNil)
-	return cmd.ResultCount, err
+func TestGetAlertsByNotificationUIDCount(t *testing.T) {
+	Convey("Given alerts sending to a notification channel", t, func() {
+		InitTestDB(t)
+
+		cmd := &models.CreateAlertNotificationCommand{
+			Uid:      "ops",
+			Name:     "ops",
+			Type:     "email",
+			OrgId:    1,
+			Settings: simplejson.New(),
+		}
+		So(CreateAlertNotificationCommand(cmd), ShouldBeNil)
+
+		byUid, _ := simplejson.NewJson([]byte(`{"notifications": [{"uid": "ops"}]}`))
+		byId, _ := simplejson.NewJson([]byte(fmt.Sprintf(`{"notifications": [{"id": %d}]}`, cmd.Result.Id)))
+		other, _ := simplejson.NewJson([]byte(`{"notifications": [{"uid": "dev"}]}`))
+
+		first, _ := insertTestAlert("By uid", "", 1, insertTestDashboard("first", 1, 0, false).Id, byUid)
+		second, _ := insertTestAlert("By id", "", 1, insertTestDashboard("second", 1, 0, false).Id, byId)
+		_, _ = insertTestAlert("Other channel", "", 1, insertTestDashboard("third", 1, 0, false).Id, other)
+
+		Convey("Should count alerts referencing the channel by uid or id", func() {
+			query := &models.GetAlertsByNotificationUIDCountQuery{OrgId: 1, NotificationUID: "ops"}
+			err := GetAlertsByNotificationUIDCount(query)
+			So(err, ShouldBeNil)
+			So(query.AlertCount, ShouldEqual, 2)
+			So(query.AlertIds, ShouldResemble, []int64{first.Id, second.Id})
+		})
+
+		Convey("Should return an error for an unknown channel", func() {
+			query := &models.GetAlertsByNotificationUIDCountQuery{OrgId: 1, NotificationUID: "unknown"}
+			err := GetAlertsByNotificationUIDCount(query)
+			So(err, ShouldEqual, models.ErrAlertNotificationNotFound)
+		})
+	})
 }
-func insertTestAlert(title string, message string, orgId int64, dashId int64, settings *simplejson.Json) (*models.Alert, error) {
-	items := []*models.Alert{
-		{
-			PanelId:     1,
-			DashboardId: dashId,
-			OrgId:       orgId,
-			Name:        title,
-			Message:     message,
-			Settings:    settings,
-			Frequency:   1,
-		},
-	}
 
-	cmd := models.SaveAlertsCommand{
-		Alerts:      items,
-		DashboardId: dashId,
-		OrgId:       orgId,
-		UserId:      1,
-	}
+func TestDeleteAlertsByNamePrefix(t *testing.T) {
+	Convey("Given alerts with different name prefixes", t, func() {
+		InitTestDB(t)
 
-	err := SaveAlerts(&cmd)
-	return cmd.Alerts[0], err
+		_, err := insertTestAlert("old_cpu", "", 1, insertTestDashboard("first", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		_, err = insertTestAlert("old_memory", "", 1, insertTestDashboard("second", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		_, err = insertTestAlert("oldXdisk", "", 1, insertTestDashboard("third", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+
+		Convey("Dry run should only count matching alerts", func() {
+			cmd := &models.DeleteAlertsByNamePrefixCommand{OrgId: 1, Prefix: "old_", DryRun: true}
+			So(DeleteAlertsByNamePrefix(cmd), ShouldBeNil)
+			So(cmd.WouldDeleteCount, ShouldEqual, 2)
+			So(cmd.DeletedCount, ShouldEqual, 0)
+		})
+
+		Convey("Should refuse to delete more alerts than the safety cap", func() {
+			cmd := &models.DeleteAlertsByNamePrefixCommand{OrgId: 1, Prefix: "old", SafetyCap: 2}
+			So(DeleteAlertsByNamePrefix(cmd), ShouldEqual, models.ErrAlertDeleteSafetyCapExceeded)
+		})
+
+		Convey("Dry run should count matching alerts above the safety cap", func() {
+			cmd := &models.DeleteAlertsByNamePrefixCommand{OrgId: 1, Prefix: "old", SafetyCap: 2, DryRun: true}
+			So(DeleteAlertsByNamePrefix(cmd), ShouldBeNil)
+			So(cmd.WouldDeleteCount, ShouldEqual, 3)
+		})
+
+		Convey("Should delete alerts matching the prefix literally", func() {
+			cmd := &models.DeleteAlertsByNamePrefixCommand{OrgId: 1, Prefix: "old_"}
+			So(DeleteAlertsByNamePrefix(cmd), ShouldBeNil)
+			So(cmd.DeletedCount, ShouldEqual, 2)
+
+			query := &models.GetAllAlertsQuery{}
+			So(GetAllAlertQueryHandler(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Name, ShouldEqual, "oldXdisk")
+		})
+	})
+}
+
+func TestGetAlertsByOrgAndStateCursor(t *testing.T) {
+	Convey("Given three alerts", t, func() {
+		InitTestDB(t)
+
+		for _, name := range []string{"first", "second", "third"} {
+			_, err := insertTestAlert(name, "", 1, insertTestDashboard(name, 1, 0, false).Id, simplejson.New())
+			So(err, ShouldBeNil)
+		}
+
+		Convey("Should page through the alerts using the cursor", func() {
+			query := &models.GetAlertsByOrgAndStateCursorQuery{OrgId: 1, States: []models.AlertStateType{models.AlertStateUnknown}, Limit: 2}
+			So(GetAlertsByOrgAndStateCursor(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.NextCursor, ShouldEqual, query.Result[1].Id)
+
+			query = &models.GetAlertsByOrgAndStateCursorQuery{OrgId: 1, AfterAlertId: query.NextCursor, Limit: 2}
+			So(GetAlertsByOrgAndStateCursor(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Name, ShouldEqual, "third")
+			So(query.NextCursor, ShouldEqual, 0)
+		})
+	})
+}
+
+func TestAlertsQueryTagFilter(t *testing.T) {
+	Convey("Given alerts of different teams", t, func() {
+		InitTestDB(t)
+
+		teamA, _ := simplejson.NewJson([]byte(`{"alertRuleTags": {"team": "a", "env": "prod"}}`))
+		teamB, _ := simplejson.NewJson([]byte(`{"alertRuleTags": {"team": "b", "env": "prod"}}`))
+		teamC, _ := simplejson.NewJson([]byte(`{"alertRuleTags": {"team": "c"}}`))
+
+		_, err := insertTestAlert("A", "", 1, insertTestDashboard("first", 1, 0, false).Id, teamA)
+		So(err, ShouldBeNil)
+		_, err = insertTestAlert("B", "", 1, insertTestDashboard("second", 1, 0, false).Id, teamB)
+		So(err, ShouldBeNil)
+		_, err = insertTestAlert("C", "", 1, insertTestDashboard("third", 1, 0, false).Id, teamC)
+		So(err, ShouldBeNil)
+
+		admin := &models.SignedInUser{OrgRole: models.ROLE_ADMIN}
+
+		Convey("Should find alerts by their number of tags", func() {
+			query := &models.GetAlertsByTagCountQuery{OrgId: 1, MinTags: 2, MaxTags: 5}
+			So(GetAlertsByTagCount(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.Result[0].Name, ShouldEqual, "A")
+			So(query.Result[0].TagCount, ShouldEqual, 2)
+
+			query = &models.GetAlertsByTagCountQuery{OrgId: 1, MinTags: 0, MaxTags: 0}
+			So(GetAlertsByTagCount(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 0)
+		})
+
+		Convey("Should count alerts per number of tags", func() {
+			_, err := insertTestAlert("untagged", "", 1, insertTestDashboard("fourth", 1, 0, false).Id, simplejson.New())
+			So(err, ShouldBeNil)
+
+			query := &models.GetAlertsByTagCountDistributionQuery{OrgId: 1}
+			So(GetAlertsByTagCountDistribution(query), ShouldBeNil)
+			So(query.Result, ShouldResemble, []*models.TagCountBucket{
+				{TagCount: 0, AlertCount: 1},
+				{TagCount: 1, AlertCount: 1},
+				{TagCount: 2, AlertCount: 2},
+			})
+		})
+
+		Convey("Should require all tags by default", func() {
+			query := &models.GetAlertsQuery{OrgId: 1, User: admin, Tags: []string{"team:a", "env:prod"}}
+			So(HandleAlertsQuery(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Name, ShouldEqual, "A")
+		})
+
+		Convey("Should match any tag when TagsMatchAny is set", func() {
+			query := &models.GetAlertsQuery{OrgId: 1, User: admin, Tags: []string{"team:a", "team:b"}, TagsMatchAny: true}
+			So(HandleAlertsQuery(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.Result[0].Name, ShouldEqual, "A")
+			So(query.Result[1].Name, ShouldEqual, "B")
+		})
+	})
+}
+
+func TestGetAlertStatesAt(t *testing.T) {
+	Convey("Given the state history of two alerts", t, func() {
+		InitTestDB(t)
+
+		repo := SqlAnnotationRepo{}
+		history := []*annotations.Item{
+			{OrgId: 1, AlertId: 1, NewState: "pending", Epoch: 1000},
+			{OrgId: 1, AlertId: 1, NewState: "alerting", Epoch: 2000},
+			{OrgId: 1, AlertId: 1, NewState: "ok", Epoch: 4000},
+			{OrgId: 1, AlertId: 2, NewState: "no_data", Epoch: 3000},
+			{OrgId: 1, DashboardId: 1, Text: "not an alert", Epoch: 2500},
+		}
+		for _, item := range history {
+			So(repo.Save(item), ShouldBeNil)
+		}
+
+		Convey("Should return the latest state before the given time", func() {
+			query := &models.GetAlertStatesAtQuery{OrgId: 1, At: time.Unix(3, 0)}
+			So(GetAlertStatesAt(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.Result[0].AlertId, ShouldEqual, 1)
+			So(query.Result[0].State, ShouldEqual, models.AlertStateAlerting)
+			So(query.Result[0].NewStateDate.Unix(), ShouldEqual, 2)
+			So(query.Result[1].AlertId, ShouldEqual, 2)
+			So(query.Result[1].State, ShouldEqual, models.AlertStateNoData)
+		})
+
+		Convey("Should leave out alerts without history at that time", func() {
+			query := &models.GetAlertStatesAtQuery{OrgId: 1, At: time.Unix(1, 500000000)}
+			So(GetAlertStatesAt(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].State, ShouldEqual, models.AlertStatePending)
+		})
+	})
+}
+
+func TestGetFiringByHourOfDay(t *testing.T) {
+	Convey("Given transitions to alerting at different hours", t, func() {
+		InitTestDB(t)
+
+		hour := int64(time.Hour / time.Millisecond)
+		repo := SqlAnnotationRepo{}
+		history := []*annotations.Item{
+			{OrgId: 1, AlertId: 1, NewState: "alerting", Epoch: 3*hour + 1000},
+			{OrgId: 1, AlertId: 2, NewState: "alerting", Epoch: 27*hour + 2000},
+			{OrgId: 1, AlertId: 1, NewState: "ok", Epoch: 3*hour + 5000},
+			{OrgId: 1, AlertId: 1, NewState: "alerting", Epoch: 5*hour + 1000},
+			{OrgId: 2, AlertId: 3, NewState: "alerting", Epoch: 3*hour + 1000},
+		}
+		for _, item := range history {
+			So(repo.Save(item), ShouldBeNil)
+		}
+
+		Convey("Should count transitions to alerting per hour of the day", func() {
+			query := &models.GetFiringByHourOfDayQuery{OrgId: 1, From: time.Unix(0, 0), To: time.Unix(2*24*3600, 0)}
+			So(GetFiringByHourOfDay(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 24)
+			So(query.Result[3].Count, ShouldEqual, 2)
+			So(query.Result[5].Count, ShouldEqual, 1)
+			So(query.Result[0].Count, ShouldEqual, 0)
+		})
+
+		Convey("Should only count transitions in the window", func() {
+			query := &models.GetFiringByHourOfDayQuery{OrgId: 1, From: time.Unix(0, 0), To: time.Unix(24*3600, 0)}
+			So(GetFiringByHourOfDay(query), ShouldBeNil)
+			So(query.Result[3].Count, ShouldEqual, 1)
+		})
+	})
+}
+
+func TestGetAlertDetail(t *testing.T) {
+	Convey("Given an alert with tags and notification channels", t, func() {
+		InitTestDB(t)
+
+		ops := &models.CreateAlertNotificationCommand{Uid: "ops", Name: "Ops", Type: "email", OrgId: 1, Settings: simplejson.New()}
+		So(CreateAlertNotificationCommand(ops), ShouldBeNil)
+		dev := &models.CreateAlertNotificationCommand{Uid: "dev", Name: "Dev", Type: "email", OrgId: 1, Settings: simplejson.New()}
+		So(CreateAlertNotificationCommand(dev), ShouldBeNil)
+
+		settings, _ := simplejson.NewJson([]byte(fmt.Sprintf(`{
+			"alertRuleTags": {"env": "prod", "team": "backend"},
+			"notifications": [{"uid": "ops"}, {"id": %d}, {"uid": "deleted"}]
+		}`, dev.Result.Id)))
+		dashboard := insertTestDashboard("dashboard with alert", 1, 0, false)
+		alert, err := insertTestAlert("Detailed", "", 1, dashboard.Id, settings)
+		So(err, ShouldBeNil)
+
+		Convey("Should assemble the alert detail", func() {
+			query := &models.GetAlertDetailQuery{Id: alert.Id, OrgId: 1}
+			So(GetAlertDetail(query), ShouldBeNil)
+
+			So(query.Result.Alert.Name, ShouldEqual, "Detailed")
+			So(query.Result.DashboardUid, ShouldEqual, dashboard.Uid)
+			So(query.Result.DashboardTitle, ShouldEqual, "dashboard with alert")
+
+			So(query.Result.Tags, ShouldHaveLength, 2)
+			So(query.Result.Tags[0].Key, ShouldEqual, "env")
+			So(query.Result.Tags[1].Value, ShouldEqual, "backend")
+
+			So(query.Result.Notifications, ShouldHaveLength, 2)
+			So(query.Result.Notifications[0].Name, ShouldEqual, "Ops")
+			So(query.Result.Notifications[1].Uid, ShouldEqual, "dev")
+		})
+
+		Convey("Should not return the alert to another org", func() {
+			query := &models.GetAlertDetailQuery{Id: alert.Id, OrgId: 2}
+			So(GetAlertDetail(query), ShouldEqual, models.ErrAlertNotFound)
+		})
+	})
+}
+
+func TestAlertsWithDanglingNotifications(t *testing.T) {
+	Convey("Given alerts referencing deleted notification channels", t, func() {
+		InitTestDB(t)
+
+		ops := &models.CreateAlertNotificationCommand{Uid: "ops", Name: "Ops", Type: "email", OrgId: 1, Settings: simplejson.New()}
+		So(CreateAlertNotificationCommand(ops), ShouldBeNil)
+
+		dangling, _ := simplejson.NewJson([]byte(`{"notifications": [{"uid": "ops"}, {"uid": "deleted"}, {"id": 999}]}`))
+		healthy, _ := simplejson.NewJson([]byte(`{"notifications": [{"uid": "ops"}]}`))
+
+		danglingAlert, err := insertTestAlert("Dangling", "", 1, insertTestDashboard("first", 1, 0, false).Id, dangling)
+		So(err, ShouldBeNil)
+		_, err = insertTestAlert("Healthy", "", 1, insertTestDashboard("second", 1, 0, false).Id, healthy)
+		So(err, ShouldBeNil)
+
+		Convey("Should find the dangling references", func() {
+			query := &models.GetAlertsWithDanglingNotificationsQuery{OrgId: 1}
+			So(GetAlertsWithDanglingNotifications(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Id, ShouldEqual, danglingAlert.Id)
+			So(query.Result[0].DanglingNotifications, ShouldResemble, []*models.AlertNotificationRef{{Uid: "deleted"}, {Id: 999}})
+		})
+
+		Convey("Should purge the dangling references", func() {
+			cmd := &models.PurgeDanglingAlertNotificationsCommand{OrgId: 1}
+			So(PurgeDanglingAlertNotifications(cmd), ShouldBeNil)
+			So(cmd.ResultCount, ShouldEqual, 1)
+
+			alert, _ := getAlertById(danglingAlert.Id)
+			So(alert.GetNotificationsFromSettings(), ShouldResemble, []*models.AlertNotificationRef{{Uid: "ops"}})
+
+			query := &models.GetAlertsWithDanglingNotificationsQuery{OrgId: 1}
+			So(GetAlertsWithDanglingNotifications(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 0)
+		})
+
+		Convey("Should purge the dangling references from the panel json", func() {
+			dash, _ := insertTestAlertPanel("panel", 1, `{"name": "panel", "notifications": [{"uid": "deleted"}, {"uid": "ops"}]}`)
+
+			cmd := &models.PurgeDanglingAlertNotificationsCommand{OrgId: 1}
+			So(PurgeDanglingAlertNotifications(cmd), ShouldBeNil)
+			So(cmd.ResultCount, ShouldEqual, 2)
+
+			notifications := getTestPanelAlert(dash.Id).Get("notifications")
+			So(notifications.MustArray(), ShouldHaveLength, 1)
+			So(notifications.GetIndex(0).Get("uid").MustString(), ShouldEqual, "ops")
+		})
+	})
+}
+
+func TestAlertsQueryPaging(t *testing.T) {
+	Convey("Given three alerts", t, func() {
+		InitTestDB(t)
+
+		for _, name := range []string{"a", "b", "c"} {
+			_, err := insertTestAlert(name, "", 1, insertTestDashboard(name, 1, 0, false).Id, simplejson.New())
+			So(err, ShouldBeNil)
+		}
+
+		admin := &models.SignedInUser{OrgRole: models.ROLE_ADMIN}
+
+		Convey("Should return a page with paging metadata", func() {
+			query := &models.GetAlertsQuery{OrgId: 1, User: admin, Page: &models.AlertsPage{PageNumber: 2, PageSize: 2}}
+			So(HandleAlertsQuery(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Name, ShouldEqual, "c")
+			So(query.Paging, ShouldResemble, &models.AlertsPaging{Total: 3, Page: 2, PageSize: 2, TotalPages: 2})
+		})
+
+		Convey("Should count the matching alerts per state", func() {
+			So(SetAlertState(&models.SetAlertStateCommand{AlertId: 1, OrgId: 1, State: models.AlertStateAlerting}), ShouldBeNil)
+
+			query := &models.GetAlertsQuery{OrgId: 1, User: admin, Limit: 1, WithStateCounts: true}
+			So(HandleAlertsQuery(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.StateCounts, ShouldResemble, map[models.AlertStateType]int64{
+				models.AlertStateAlerting: 1,
+				models.AlertStateUnknown:  2,
+			})
+
+			query = &models.GetAlertsQuery{OrgId: 1, User: admin, Query: "b", WithStateCounts: true}
+			So(HandleAlertsQuery(query), ShouldBeNil)
+			So(query.StateCounts, ShouldResemble, map[models.AlertStateType]int64{models.AlertStateUnknown: 1})
+		})
+
+		Convey("Should keep honoring Limit without a page", func() {
+			query := &models.GetAlertsQuery{OrgId: 1, User: admin, Limit: 2}
+			So(HandleAlertsQuery(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.Paging, ShouldBeNil)
+		})
+
+		Convey("Should skip the alerts before the offset", func() {
+			query := &models.GetAlertsQuery{OrgId: 1, User: admin, Limit: 1, Offset: 1}
+			So(HandleAlertsQuery(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Name, ShouldEqual, "b")
+
+			query = &models.GetAlertsQuery{OrgId: 1, User: admin, Offset: 2}
+			So(HandleAlertsQuery(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Name, ShouldEqual, "c")
+		})
+	})
+}
+
+func TestImportAlertTags(t *testing.T) {
+	Convey("Given a tagged alert", t, func() {
+		InitTestDB(t)
+
+		settings, _ := simplejson.NewJson([]byte(`{"alertRuleTags": {"env": "staging"}}`))
+		alert, err := insertTestAlert("tagged", "", 1, insertTestDashboard("first", 1, 0, false).Id, settings)
+		So(err, ShouldBeNil)
+
+		Convey("Should replace its tags and report unknown alerts", func() {
+			cmd := &models.ImportAlertTagsCommand{
+				OrgId: 1,
+				Tags: map[int64][]*models.Tag{
+					alert.Id: {{Key: "env", Value: "prod"}, {Key: "team", Value: "sre"}},
+					9999:     {{Key: "env", Value: "prod"}},
+				},
+			}
+			So(ImportAlertTags(cmd), ShouldBeNil)
+			So(cmd.Result, ShouldResemble, []*models.ImportAlertTagsResult{
+				{AlertId: alert.Id, TagCount: 2},
+				{AlertId: 9999, Error: models.ErrAlertNotFound.Error()},
+			})
+
+			admin := &models.SignedInUser{OrgRole: models.ROLE_ADMIN}
+			query := &models.GetAlertsQuery{OrgId: 1, User: admin, Tags: []string{"env:prod", "team:sre"}}
+			So(HandleAlertsQuery(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+
+			query = &models.GetAlertsQuery{OrgId: 1, User: admin, Tags: []string{"env:staging"}}
+			So(HandleAlertsQuery(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 0)
+
+			updated, _ := getAlertById(alert.Id)
+			So(updated.Settings.Get("alertRuleTags").Get("team").MustString(), ShouldEqual, "sre")
+		})
+
+		Convey("Should replace the tags in the panel json", func() {
+			dash, panelAlert := insertTestAlertPanel("panel", 1, `{"name": "panel", "alertRuleTags": {"env": "staging"}}`)
+
+			cmd := &models.ImportAlertTagsCommand{
+				OrgId: 1,
+				Tags:  map[int64][]*models.Tag{panelAlert.Id: {{Key: "team", Value: "sre"}}},
+			}
+			So(ImportAlertTags(cmd), ShouldBeNil)
+			So(getTestPanelAlert(dash.Id).Get("alertRuleTags").MustMap(), ShouldResemble, map[string]interface{}{"team": "sre"})
+		})
+	})
+}
+
+func TestGetAlertsBySettingsKey(t *testing.T) {
+	Convey("Given alerts with and without a settings key", t, func() {
+		InitTestDB(t)
+
+		withKey, _ := simplejson.NewJson([]byte(`{"noDataState": "keep_state", "frequency": "60s"}`))
+		withNullKey, _ := simplejson.NewJson([]byte(`{"noDataState": null}`))
+		withoutKey, _ := simplejson.NewJson([]byte(`{"frequency": "60s", "conditions": [{"noDataState": "alerting"}]}`))
+
+		_, err := insertTestAlert("with key", "", 1, insertTestDashboard("first", 1, 0, false).Id, withKey)
+		So(err, ShouldBeNil)
+		_, err = insertTestAlert("with null key", "", 1, insertTestDashboard("second", 1, 0, false).Id, withNullKey)
+		So(err, ShouldBeNil)
+		_, err = insertTestAlert("without key", "", 1, insertTestDashboard("third", 1, 0, false).Id, withoutKey)
+		So(err, ShouldBeNil)
+		_, err = insertTestAlert("other org", "", 2, insertTestDashboard("fourth", 2, 0, false).Id, withKey)
+		So(err, ShouldBeNil)
+
+		Convey("Should only return the alerts having the top level key", func() {
+			query := &models.GetAlertsBySettingsKeyQuery{OrgId: 1, Key: "noDataState"}
+			So(GetAlertsBySettingsKey(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.Result[0].Name, ShouldEqual, "with key")
+			So(query.Result[1].Name, ShouldEqual, "with null key")
+		})
+
+		Convey("Should return nothing for an unused key", func() {
+			query := &models.GetAlertsBySettingsKeyQuery{OrgId: 1, Key: "unused"}
+			So(GetAlertsBySettingsKey(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 0)
+		})
+	})
+}
+
+func TestAlertsQueryConditionTypeFilter(t *testing.T) {
+	Convey("Given alerts with different reducers and evaluators", t, func() {
+		InitTestDB(t)
+
+		avgGt, _ := simplejson.NewJson([]byte(`{"conditions": [{"reducer": {"type": "avg"}, "evaluator": {"type": "gt"}}]}`))
+		mixed, _ := simplejson.NewJson([]byte(`{"conditions": [
+			{"reducer": {"type": "avg"}, "evaluator": {"type": "lt"}},
+			{"reducer": {"type": "max"}, "evaluator": {"type": "gt"}}
+		]}`))
+
+		_, err := insertTestAlert("avg gt", "", 1, insertTestDashboard("first", 1, 0, false).Id, avgGt)
+		So(err, ShouldBeNil)
+		_, err = insertTestAlert("mixed", "", 1, insertTestDashboard("second", 1, 0, false).Id, mixed)
+		So(err, ShouldBeNil)
+
+		admin := &models.SignedInUser{OrgRole: models.ROLE_ADMIN}
+
+		Convey("Should filter on the reducer type", func() {
+			query := &models.GetAlertsQuery{OrgId: 1, User: admin, ReducerType: "avg"}
+			So(HandleAlertsQuery(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+		})
+
+		Convey("Should require both types on the same condition", func() {
+			query := &models.GetAlertsQuery{OrgId: 1, User: admin, ReducerType: "avg", EvaluatorType: "gt"}
+			So(HandleAlertsQuery(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Name, ShouldEqual, "avg gt")
+		})
+
+		Convey("Should return nothing for an unused type", func() {
+			query := &models.GetAlertsQuery{OrgId: 1, User: admin, ReducerType: "median"}
+			So(HandleAlertsQuery(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 0)
+		})
+	})
+}
+
+func TestGetAlertsByEvalDataValue(t *testing.T) {
+	Convey("Given alerts with eval matches", t, func() {
+		InitTestDB(t)
+
+		high, _ := insertTestAlert("high", "", 1, insertTestDashboard("first", 1, 0, false).Id, simplejson.New())
+		low, _ := insertTestAlert("low", "", 1, insertTestDashboard("second", 1, 0, false).Id, simplejson.New())
+
+		for alertId, evalData := range map[int64]string{
+			high.Id: `{"evalMatches": [{"metric": "mem", "value": 99}, {"metric": "cpu", "value": 95.5}]}`,
+			low.Id:  `{"evalMatches": [{"metric": "cpu", "value": 40}, {"metric": "cpu", "value": null}]}`,
+		} {
+			data, _ := simplejson.NewJson([]byte(evalData))
+			So(SetAlertState(&models.SetAlertStateCommand{AlertId: alertId, OrgId: 1, State: models.AlertStateAlerting, EvalData: data}), ShouldBeNil)
+		}
+
+		Convey("Should return the alerts with a value above the bound", func() {
+			gt := 90.0
+			query := &models.GetAlertsByEvalDataValueQuery{OrgId: 1, MetricName: "cpu", GtValue: &gt}
+			So(GetAlertsByEvalDataValue(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Id, ShouldEqual, high.Id)
+			So(query.Result[0].MatchingValue, ShouldEqual, 95.5)
+		})
+
+		Convey("Should return the alerts with a value between the bounds", func() {
+			gt, lt := 10.0, 50.0
+			query := &models.GetAlertsByEvalDataValueQuery{OrgId: 1, MetricName: "cpu", GtValue: &gt, LtValue: &lt}
+			So(GetAlertsByEvalDataValue(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Id, ShouldEqual, low.Id)
+		})
+	})
+}
+
+func TestRenameAlertTagValue(t *testing.T) {
+	Convey("Given alerts tagged with a team in two orgs", t, func() {
+		InitTestDB(t)
+
+		settings, _ := simplejson.NewJson([]byte(`{"alertRuleTags": {"team": "core", "env": "prod"}}`))
+		renamed, err := insertTestAlert("renamed", "", 1, insertTestDashboard("first", 1, 0, false).Id, settings)
+		So(err, ShouldBeNil)
+		otherOrg, err := insertTestAlert("other org", "", 2, insertTestDashboard("second", 2, 0, false).Id, settings)
+		So(err, ShouldBeNil)
+
+		Convey("Should only rename the tag in the org", func() {
+			cmd := &models.RenameAlertTagValueCommand{OrgId: 1, Key: "team", OldValue: "core", NewValue: "platform"}
+			So(RenameAlertTagValue(cmd), ShouldBeNil)
+			So(cmd.ResultCount, ShouldEqual, 1)
+
+			alert, _ := getAlertById(renamed.Id)
+			So(alert.Settings.Get("alertRuleTags").Get("team").MustString(), ShouldEqual, "platform")
+			So(alert.Settings.Get("alertRuleTags").Get("env").MustString(), ShouldEqual, "prod")
+
+			query := &models.GetAlertsQuery{OrgId: 1, User: &models.SignedInUser{OrgRole: models.ROLE_ADMIN}, Tags: []string{"team:platform", "env:prod"}}
+			So(HandleAlertsQuery(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+
+			alert, _ = getAlertById(otherOrg.Id)
+			So(alert.Settings.Get("alertRuleTags").Get("team").MustString(), ShouldEqual, "core")
+
+			query = &models.GetAlertsQuery{OrgId: 2, User: &models.SignedInUser{OrgRole: models.ROLE_ADMIN}, Tags: []string{"team:core"}}
+			So(HandleAlertsQuery(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+		})
+
+		Convey("Should rename the tag in the panel json", func() {
+			dash, _ := insertTestAlertPanel("panel", 1, `{"name": "panel", "alertRuleTags": {"team": "core", "env": "prod"}}`)
+
+			cmd := &models.RenameAlertTagValueCommand{OrgId: 1, Key: "team", OldValue: "core", NewValue: "platform"}
+			So(RenameAlertTagValue(cmd), ShouldBeNil)
+			So(cmd.ResultCount, ShouldEqual, 2)
+
+			tags := getTestPanelAlert(dash.Id).Get("alertRuleTags")
+			So(tags.Get("team").MustString(), ShouldEqual, "platform")
+			So(tags.Get("env").MustString(), ShouldEqual, "prod")
+		})
+	})
+}
+
+func TestGetDuplicateAlertsOnDashboard(t *testing.T) {
+	Convey("Given a dashboard with a copied alert panel", t, func() {
+		InitTestDB(t)
+
+		dash := insertTestDashboard("copied", 1, 0, false)
+		settings, _ := simplejson.NewJson([]byte(`{"frequency": "60s", "conditions": []}`))
+		other, _ := simplejson.NewJson([]byte(`{"frequency": "10s", "conditions": []}`))
+
+		cmd := models.SaveAlertsCommand{
+			DashboardId: dash.Id,
+			OrgId:       1,
+			UserId:      1,
+			Alerts: []*models.Alert{
+				{PanelId: 1, DashboardId: dash.Id, OrgId: 1, Name: "CPU", Settings: settings, Frequency: 60},
+				{PanelId: 2, DashboardId: dash.Id, OrgId: 1, Name: "CPU", Settings: settings, Frequency: 60},
+				{PanelId: 3, DashboardId: dash.Id, OrgId: 1, Name: "CPU", Settings: other, Frequency: 10},
+			},
+		}
+		So(SaveAlerts(&cmd), ShouldBeNil)
+
+		Convey("Should group the alerts with the same name and settings", func() {
+			query := &models.GetDuplicateAlertsOnDashboardQuery{OrgId: 1, DashboardId: dash.Id}
+			So(GetDuplicateAlertsOnDashboard(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Name, ShouldEqual, "CPU")
+			So(query.Result[0].Alerts, ShouldHaveLength, 2)
+			So(query.Result[0].Alerts[0].PanelId, ShouldEqual, 1)
+			So(query.Result[0].Alerts[1].PanelId, ShouldEqual, 2)
+		})
+	})
+}
+
+func TestCompareOrgAlerts(t *testing.T) {
+	Convey("Given alerts in a staging and a production org", t, func() {
+		InitTestDB(t)
+
+		fast, _ := simplejson.NewJson([]byte(`{"frequency": "10s"}`))
+		slow, _ := simplejson.NewJson([]byte(`{"frequency": "60s"}`))
+
+		staging := insertTestDashboard("staging", 1, 0, false).Id
+		prod := insertTestDashboard("prod", 2, 0, false).Id
+		stagingAlerts := []*models.Alert{
+			{PanelId: 1, Name: "same", Settings: fast},
+			{PanelId: 2, Name: "drifted", Settings: fast},
+			{PanelId: 3, Name: "staging only", Settings: fast},
+			{PanelId: 4, Name: "twice", Settings: fast},
+			{PanelId: 5, Name: "twice", Settings: fast},
+		}
+		for _, alert := range stagingAlerts {
+			alert.DashboardId, alert.OrgId, alert.Frequency = staging, 1, 1
+		}
+		So(SaveAlerts(&models.SaveAlertsCommand{DashboardId: staging, OrgId: 1, UserId: 1, Alerts: stagingAlerts}), ShouldBeNil)
+
+		prodAlerts := []*models.Alert{
+			{PanelId: 1, Name: "same", Settings: fast},
+			{PanelId: 2, Name: "drifted", Settings: slow},
+			{PanelId: 3, Name: "prod only", Settings: fast},
+			{PanelId: 4, Name: "twice", Settings: fast},
+		}
+		for _, alert := range prodAlerts {
+			alert.DashboardId, alert.OrgId, alert.Frequency = prod, 2, 1
+		}
+		So(SaveAlerts(&models.SaveAlertsCommand{DashboardId: prod, OrgId: 2, UserId: 1, Alerts: prodAlerts}), ShouldBeNil)
+
+		Convey("Should report the drift between the orgs", func() {
+			query := &models.CompareOrgAlertsQuery{OrgIdA: 1, OrgIdB: 2}
+			So(CompareOrgAlerts(query), ShouldBeNil)
+			So(query.Result.OnlyInA, ShouldResemble, []string{"staging only"})
+			So(query.Result.OnlyInB, ShouldResemble, []string{"prod only"})
+			So(query.Result.AmbiguousNames, ShouldResemble, []string{"twice"})
+			So(query.Result.Differing, ShouldHaveLength, 1)
+			So(query.Result.Differing[0].Name, ShouldEqual, "drifted")
+		})
+	})
+
+	Convey("Given the same alert in orgs with different datasource and notification ids", t, func() {
+		InitTestDB(t)
+
+		for _, orgId := range []int64{1, 2} {
+			// a channel only in org 2 gives the channels different ids
+			if orgId == 2 {
+				other := &models.CreateAlertNotificationCommand{Uid: "other", Name: "Other", Type: "email", OrgId: orgId, Settings: simplejson.New()}
+				So(CreateAlertNotificationCommand(other), ShouldBeNil)
+			}
+			ops := &models.CreateAlertNotificationCommand{Uid: "ops", Name: "Ops", Type: "email", OrgId: orgId, Settings: simplejson.New()}
+			So(CreateAlertNotificationCommand(ops), ShouldBeNil)
+
+			settings, _ := simplejson.NewJson([]byte(fmt.Sprintf(`{
+				"conditions": [{"query": {"datasourceId": %d, "params": ["A", "5m", "now"]}}],
+				"notifications": [{"id": %d}]
+			}`, orgId*10, ops.Result.Id)))
+			_, err := insertTestAlert("cpu", "", orgId, insertTestDashboard("cpu", orgId, 0, false).Id, settings)
+			So(err, ShouldBeNil)
+		}
+
+		Convey("Should not report the alert as differing", func() {
+			query := &models.CompareOrgAlertsQuery{OrgIdA: 1, OrgIdB: 2}
+			So(CompareOrgAlerts(query), ShouldBeNil)
+			So(query.Result.Differing, ShouldHaveLength, 0)
+		})
+	})
+}
+
+func TestReplaceNotificationChannel(t *testing.T) {
+	Convey("Given alerts sending to an old channel", t, func() {
+		InitTestDB(t)
+
+		oldChannel := &models.CreateAlertNotificationCommand{Uid: "old", Name: "Old", Type: "email", OrgId: 1, Settings: simplejson.New()}
+		So(CreateAlertNotificationCommand(oldChannel), ShouldBeNil)
+		newChannel := &models.CreateAlertNotificationCommand{Uid: "new", Name: "New", Type: "email", OrgId: 1, Settings: simplejson.New()}
+		So(CreateAlertNotificationCommand(newChannel), ShouldBeNil)
+
+		byId, _ := simplejson.NewJson([]byte(fmt.Sprintf(`{"alertRuleTags": {"env": "prod"}, "notifications": [{"id": %d}, {"uid": "other"}]}`, oldChannel.Result.Id)))
+		both, _ := simplejson.NewJson([]byte(`{"alertRuleTags": {"env": "staging"}, "notifications": [{"uid": "old"}, {"uid": "new"}]}`))
+
+		prodAlert, err := insertTestAlert("prod", "", 1, insertTestDashboard("first", 1, 0, false).Id, byId)
+		So(err, ShouldBeNil)
+		stagingAlert, err := insertTestAlert("staging", "", 1, insertTestDashboard("second", 1, 0, false).Id, both)
+		So(err, ShouldBeNil)
+
+		Convey("Should replace the channel on all alerts", func() {
+			cmd := &models.ReplaceNotificationChannelCommand{OrgId: 1, OldUid: "old", NewUid: "new"}
+			So(ReplaceNotificationChannel(cmd), ShouldBeNil)
+			So(cmd.ResultCount, ShouldEqual, 2)
+
+			alert, _ := getAlertById(prodAlert.Id)
+			So(alert.GetNotificationsFromSettings(), ShouldResemble, []*models.AlertNotificationRef{{Uid: "new"}, {Uid: "other"}})
+			alert, _ = getAlertById(stagingAlert.Id)
+			So(alert.GetNotificationsFromSettings(), ShouldResemble, []*models.AlertNotificationRef{{Uid: "new"}})
+		})
+
+		Convey("Should only replace the channel on alerts matching the filter", func() {
+			cmd := &models.ReplaceNotificationChannelCommand{OrgId: 1, OldUid: "old", NewUid: "new", Filter: &models.GetAlertsQuery{Tags: []string{"env:staging"}}}
+			So(ReplaceNotificationChannel(cmd), ShouldBeNil)
+			So(cmd.ResultCount, ShouldEqual, 1)
+
+			alert, _ := getAlertById(prodAlert.Id)
+			So(alert.GetNotificationsFromSettings()[0].Id, ShouldEqual, oldChannel.Result.Id)
+		})
+
+		Convey("Should replace the channel in the panel json", func() {
+			dash, _ := insertTestAlertPanel("panel", 1, `{"name": "panel", "notifications": [{"uid": "old"}]}`)
+
+			cmd := &models.ReplaceNotificationChannelCommand{OrgId: 1, OldUid: "old", NewUid: "new"}
+			So(ReplaceNotificationChannel(cmd), ShouldBeNil)
+			So(cmd.ResultCount, ShouldEqual, 3)
+
+			notifications := getTestPanelAlert(dash.Id).Get("notifications")
+			So(notifications.MustArray(), ShouldHaveLength, 1)
+			So(notifications.GetIndex(0).Get("uid").MustString(), ShouldEqual, "new")
+		})
+
+		Convey("Should fail when the new channel does not exist", func() {
+			cmd := &models.ReplaceNotificationChannelCommand{OrgId: 1, OldUid: "old", NewUid: "missing"}
+			So(ReplaceNotificationChannel(cmd), ShouldEqual, models.ErrAlertNotificationNotFound)
+		})
+	})
+}
+
+func TestGetAlertsByPauseReason(t *testing.T) {
+	Convey("Given alerts paused for different reasons", t, func() {
+		InitTestDB(t)
+
+		first, err := insertTestAlert("first", "", 1, insertTestDashboard("first", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		second, err := insertTestAlert("second", "", 1, insertTestDashboard("second", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+
+		So(PauseAlert(&models.PauseAlertCommand{OrgId: 1, AlertIds: []int64{first.Id}, Paused: true, PauseReason: "Planned maintenance", PausedBy: 3}), ShouldBeNil)
+		So(PauseAlert(&models.PauseAlertCommand{OrgId: 1, AlertIds: []int64{second.Id}, Paused: true, PauseReason: "Too noisy", PausedBy: 4}), ShouldBeNil)
+
+		Convey("Should return the alerts whose pause reason matches", func() {
+			query := &models.GetAlertsByPauseReasonQuery{OrgId: 1, PauseReason: "maintenance"}
+			So(GetAlertsByPauseReason(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Id, ShouldEqual, first.Id)
+			So(query.Result[0].PauseReason, ShouldEqual, "Planned maintenance")
+			So(query.Result[0].PausedBy, ShouldEqual, 3)
+		})
+
+		Convey("Should clear the pause reason when unpausing", func() {
+			So(PauseAlert(&models.PauseAlertCommand{OrgId: 1, AlertIds: []int64{first.Id}, Paused: false}), ShouldBeNil)
+
+			alert, err := getAlertById(first.Id)
+			So(err, ShouldBeNil)
+			So(alert.PauseReason, ShouldEqual, "")
+			So(alert.PausedBy, ShouldEqual, 0)
+
+			query := &models.GetAlertsByPauseReasonQuery{OrgId: 1}
+			So(GetAlertsByPauseReason(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Id, ShouldEqual, second.Id)
+		})
+
+		Convey("Should return alerts paused without a reason when the reason is empty", func() {
+			legacy, err := insertTestAlert("legacy", "", 1, insertTestDashboard("legacy", 1, 0, false).Id, simplejson.New())
+			So(err, ShouldBeNil)
+			_, err = pauseAlert(1, legacy.Id, true)
+			So(err, ShouldBeNil)
+			_, err = x.Exec("UPDATE alert SET pause_reason = NULL WHERE id = ?", legacy.Id)
+			So(err, ShouldBeNil)
+
+			query := &models.GetAlertsByPauseReasonQuery{OrgId: 1}
+			So(GetAlertsByPauseReason(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 3)
+			So(query.Result[1].Id, ShouldEqual, legacy.Id)
+		})
+	})
+}
+
+func TestGetAlertsWithFewEvaluationsInFor(t *testing.T) {
+	Convey("Given alerts with different For durations and frequencies", t, func() {
+		InitTestDB(t)
+
+		dash := insertTestDashboard("for", 1, 0, false)
+		cmd := models.SaveAlertsCommand{
+			DashboardId: dash.Id,
+			OrgId:       1,
+			UserId:      1,
+			Alerts: []*models.Alert{
+				{PanelId: 1, DashboardId: dash.Id, OrgId: 1, Name: "often", Settings: simplejson.New(), Frequency: 300, For: time.Hour},
+				{PanelId: 2, DashboardId: dash.Id, OrgId: 1, Name: "rarely", Settings: simplejson.New(), Frequency: 1800, For: time.Hour},
+				{PanelId: 3, DashboardId: dash.Id, OrgId: 1, Name: "default", Settings: simplejson.New(), Frequency: 0, For: 3 * time.Minute},
+				{PanelId: 4, DashboardId: dash.Id, OrgId: 1, Name: "without for", Settings: simplejson.New(), Frequency: 1800},
+			},
+		}
+		So(SaveAlerts(&cmd), ShouldBeNil)
+
+		Convey("Should return the alerts evaluated too few times within For", func() {
+			query := &models.GetAlertsWithFewEvaluationsInForQuery{OrgId: 1, MinEvaluations: 4}
+			So(GetAlertsWithFewEvaluationsInFor(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+
+			So(query.Result[0].Name, ShouldEqual, "rarely")
+			So(query.Result[0].Frequency, ShouldEqual, 1800)
+			So(query.Result[0].ForDuration, ShouldEqual, time.Hour)
+			So(query.Result[0].EvaluationsInFor, ShouldEqual, 2)
+
+			So(query.Result[1].Name, ShouldEqual, "default")
+			So(query.Result[1].EvaluationsInFor, ShouldEqual, 3)
+		})
+	})
+}
+
+func TestGetAlertsByDashboardAndState(t *testing.T) {
+	Convey("Given a dashboard with alerts in different states", t, func() {
+		InitTestDB(t)
+
+		dash := insertTestDashboard("states", 1, 0, false)
+		other := insertTestDashboard("other", 1, 0, false)
+		_, err := insertTestAlert("other", "", 1, other.Id, simplejson.New())
+		So(err, ShouldBeNil)
+
+		cmd := models.SaveAlertsCommand{DashboardId: dash.Id, OrgId: 1, UserId: 1}
+		for _, panelId := range []int64{5, 3, 1, 4, 2} {
+			cmd.Alerts = append(cmd.Alerts, &models.Alert{
+				PanelId:     panelId,
+				DashboardId: dash.Id,
+				OrgId:       1,
+				Name:        fmt.Sprintf("panel %d", panelId),
+				Settings:    simplejson.New(),
+			})
+		}
+		So(SaveAlerts(&cmd), ShouldBeNil)
+
+		states := map[int64]models.AlertStateType{
+			1: models.AlertStateAlerting,
+			2: models.AlertStateOK,
+			3: models.AlertStateAlerting,
+			4: models.AlertStateNoData,
+			5: models.AlertStateOK,
+		}
+		for _, alert := range cmd.Alerts {
+			So(SetAlertState(&models.SetAlertStateCommand{AlertId: alert.Id, OrgId: 1, State: states[alert.PanelId]}), ShouldBeNil)
+		}
+
+		Convey("Should return all alerts ordered by panel without a state filter", func() {
+			query := &models.GetAlertsByDashboardAndStateQuery{OrgId: 1, DashboardId: dash.Id}
+			So(GetAlertsByDashboardAndState(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 5)
+			for i, alert := range query.Result {
+				So(alert.DashboardId, ShouldEqual, dash.Id)
+				So(alert.PanelId, ShouldEqual, i+1)
+				So(alert.State, ShouldEqual, states[alert.PanelId])
+			}
+		})
+
+		Convey("Should only return the alerts in the given states", func() {
+			query := &models.GetAlertsByDashboardAndStateQuery{
+				OrgId:       1,
+				DashboardId: dash.Id,
+				State:       []models.AlertStateType{models.AlertStateAlerting, models.AlertStateNoData},
+			}
+			So(GetAlertsByDashboardAndState(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 3)
+			So(query.Result[0].PanelId, ShouldEqual, 1)
+			So(query.Result[1].PanelId, ShouldEqual, 3)
+			So(query.Result[2].PanelId, ShouldEqual, 4)
+		})
+	})
+}
+
+func TestAlertsQueryTeamFilter(t *testing.T) {
+	Convey("Given alerts on dashboards with team permissions", t, func() {
+		InitTestDB(t)
+
+		team := models.CreateTeamCommand{Name: "ops", OrgId: 1}
+		So(CreateTeam(&team), ShouldBeNil)
+
+		folder := insertTestDashboard("ops folder", 1, 0, true)
+		inFolder := insertTestDashboard("in folder", 1, folder.Id, false)
+		viewOnly := insertTestDashboard("view only", 1, 0, false)
+		granted := insertTestDashboard("granted", 1, 0, false)
+		unrelated := insertTestDashboard("unrelated", 1, 0, false)
+
+		So(testHelperUpdateDashboardAcl(folder.Id, models.DashboardAcl{OrgId: 1, TeamId: team.Result.Id, DashboardId: folder.Id, Permission: models.PERMISSION_EDIT}), ShouldBeNil)
+		So(testHelperUpdateDashboardAcl(viewOnly.Id, models.DashboardAcl{OrgId: 1, TeamId: team.Result.Id, DashboardId: viewOnly.Id, Permission: models.PERMISSION_VIEW}), ShouldBeNil)
+		So(testHelperUpdateDashboardAcl(granted.Id, models.DashboardAcl{OrgId: 1, TeamId: team.Result.Id, DashboardId: granted.Id, Permission: models.PERMISSION_ADMIN}), ShouldBeNil)
+
+		for _, dash := range []*models.Dashboard{inFolder, viewOnly, granted, unrelated} {
+			_, err := insertTestAlert(dash.Title, "", 1, dash.Id, simplejson.New())
+			So(err, ShouldBeNil)
+		}
+
+		Convey("Should only return alerts on dashboards the team can edit", func() {
+			admin := &models.SignedInUser{OrgRole: models.ROLE_ADMIN}
+			query := models.GetAlertsQuery{OrgId: 1, TeamId: team.Result.Id, User: admin}
+			So(HandleAlertsQuery(&query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.Result[0].Name, ShouldEqual, "granted")
+			So(query.Result[1].Name, ShouldEqual, "in folder")
+		})
+	})
+}
+
+func TestExportAlertsByFilter(t *testing.T) {
+	Convey("Given alerts of different environments", t, func() {
+		InitTestDB(t)
+
+		channel := &models.CreateAlertNotificationCommand{Uid: "ops", Name: "Ops", Type: "email", OrgId: 1, Settings: simplejson.New()}
+		So(CreateAlertNotificationCommand(channel), ShouldBeNil)
+
+		prod, _ := simplejson.NewJson([]byte(fmt.Sprintf(`{"alertRuleTags": {"env": "prod"}, "notifications": [{"id": %d}, {"uid": "deleted"}]}`, channel.Result.Id)))
+		staging, _ := simplejson.NewJson([]byte(`{"alertRuleTags": {"env": "staging"}, "notifications": [{"uid": "ops"}]}`))
+
+		prodDash := insertTestDashboard("prod", 1, 0, false)
+		_, err := insertTestAlert("prod", "prod message", 1, prodDash.Id, prod)
+		So(err, ShouldBeNil)
+		_, err = insertTestAlert("staging", "", 1, insertTestDashboard("staging", 1, 0, false).Id, staging)
+		So(err, ShouldBeNil)
+
+		Convey("Should only export the alerts matching the filter", func() {
+			query := &models.ExportAlertsByFilterQuery{OrgId: 1, Filter: &models.GetAlertsQuery{Tags: []string{"env:prod"}}}
+			So(ExportAlertsByFilter(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+
+			export := query.Result[0]
+			So(export.DashboardUid, ShouldEqual, prodDash.Uid)
+			So(export.Name, ShouldEqual, "prod")
+			So(export.Message, ShouldEqual, "prod message")
+			So(export.Tags, ShouldResemble, map[string]string{"env": "prod"})
+			So(export.NotificationUids, ShouldResemble, []string{"ops"})
+
+			settings := &models.Alert{Settings: export.Settings}
+			So(settings.GetNotificationsFromSettings(), ShouldResemble, []*models.AlertNotificationRef{{Uid: "ops"}})
+		})
+
+		Convey("Should export every alert without a filter", func() {
+			query := &models.ExportAlertsByFilterQuery{OrgId: 1}
+			So(ExportAlertsByFilter(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+		})
+	})
+}
+
+func TestGetAlertCountByDashboard(t *testing.T) {
+	Convey("Given dashboards with alerts in different states", t, func() {
+		InitTestDB(t)
+
+		first := insertTestDashboard("first", 1, 0, false)
+		second := insertTestDashboard("second", 1, 0, false)
+		empty := insertTestDashboard("empty", 1, 0, false)
+
+		cmd := models.SaveAlertsCommand{
+			DashboardId: first.Id,
+			OrgId:       1,
+			UserId:      1,
+			Alerts: []*models.Alert{
+				{PanelId: 1, DashboardId: first.Id, OrgId: 1, Name: "a", Settings: simplejson.New()},
+				{PanelId: 2, DashboardId: first.Id, OrgId: 1, Name: "b", Settings: simplejson.New()},
+				{PanelId: 3, DashboardId: first.Id, OrgId: 1, Name: "c", Settings: simplejson.New()},
+			},
+		}
+		So(SaveAlerts(&cmd), ShouldBeNil)
+		So(SetAlertState(&models.SetAlertStateCommand{AlertId: cmd.Alerts[0].Id, OrgId: 1, State: models.AlertStateAlerting}), ShouldBeNil)
+
+		_, err := insertTestAlert("d", "", 1, second.Id, simplejson.New())
+		So(err, ShouldBeNil)
+
+		Convey("Should count the alerts per dashboard and state", func() {
+			query := &models.GetAlertCountByDashboardQuery{OrgId: 1, DashboardIds: []int64{first.Id, second.Id, empty.Id}}
+			So(GetAlertCountByDashboard(query), ShouldBeNil)
+			So(query.Result, ShouldResemble, map[int64]map[models.AlertStateType]int64{
+				first.Id:  {models.AlertStateAlerting: 1, models.AlertStateUnknown: 2},
+				second.Id: {models.AlertStateUnknown: 1},
+			})
+		})
+	})
+}
+
+func TestGetOverduePendingAlerts(t *testing.T) {
+	Convey("Given pending alerts", t, func() {
+		InitTestDB(t)
+		defer resetTimeNow()
+
+		dash := insertTestDashboard("pending", 1, 0, false)
+		cmd := models.SaveAlertsCommand{
+			DashboardId: dash.Id,
+			OrgId:       1,
+			UserId:      1,
+			Alerts: []*models.Alert{
+				{PanelId: 1, DashboardId: dash.Id, OrgId: 1, Name: "overdue", Settings: simplejson.New(), For: 5 * time.Minute},
+				{PanelId: 2, DashboardId: dash.Id, OrgId: 1, Name: "still pending", Settings: simplejson.New(), For: 2 * time.Hour},
+				{PanelId: 3, DashboardId: dash.Id, OrgId: 1, Name: "alerting", Settings: simplejson.New(), For: 5 * time.Minute},
+			},
+		}
+		So(SaveAlerts(&cmd), ShouldBeNil)
+
+		now := time.Now()
+		timeNow = func() time.Time { return now.Add(-time.Hour) }
+		So(SetAlertState(&models.SetAlertStateCommand{AlertId: cmd.Alerts[0].Id, OrgId: 1, State: models.AlertStatePending}), ShouldBeNil)
+		So(SetAlertState(&models.SetAlertStateCommand{AlertId: cmd.Alerts[1].Id, OrgId: 1, State: models.AlertStatePending}), ShouldBeNil)
+		So(SetAlertState(&models.SetAlertStateCommand{AlertId: cmd.Alerts[2].Id, OrgId: 1, State: models.AlertStateAlerting}), ShouldBeNil)
+		timeNow = func() time.Time { return now }
+
+		Convey("Should return the alerts pending for longer than their For", func() {
+			query := &models.GetOverduePendingAlertsQuery{OrgId: 1}
+			So(GetOverduePendingAlerts(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Name, ShouldEqual, "overdue")
+			So(query.Result[0].ForDuration, ShouldEqual, 5*time.Minute)
+			So(query.Result[0].Overdue, ShouldAlmostEqual, 55*time.Minute, time.Second)
+		})
+	})
+}
+
+func TestGetAlertsByAnnotationCount(t *testing.T) {
+	Convey("Given alerts with state change annotations", t, func() {
+		InitTestDB(t)
+
+		noisy, err := insertTestAlert("noisy", "", 1, insertTestDashboard("noisy", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		quiet, err := insertTestAlert("quiet", "", 1, insertTestDashboard("quiet", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+
+		repo := SqlAnnotationRepo{}
+		for i := 0; i < 3; i++ {
+			So(repo.Save(&annotations.Item{OrgId: 1, AlertId: noisy.Id, NewState: "alerting", Epoch: int64(i)}), ShouldBeNil)
+		}
+		So(repo.Save(&annotations.Item{OrgId: 1, AlertId: quiet.Id, NewState: "alerting", Epoch: 1}), ShouldBeNil)
+		So(repo.Save(&annotations.Item{OrgId: 1, DashboardId: 1, Text: "not an alert", Epoch: 1}), ShouldBeNil)
+
+		Convey("Should return the alerts with at least the given number of annotations", func() {
+			query := &models.GetAlertsByAnnotationCountQuery{OrgId: 1, MinAnnotations: 2}
+			So(GetAlertsByAnnotationCount(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Id, ShouldEqual, noisy.Id)
+			So(query.Result[0].AnnotationCount, ShouldEqual, 3)
+		})
+
+		Convey("Should order the alerts by annotation count", func() {
+			query := &models.GetAlertsByAnnotationCountQuery{OrgId: 1, MinAnnotations: 1}
+			So(GetAlertsByAnnotationCount(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.Result[0].Id, ShouldEqual, noisy.Id)
+			So(query.Result[1].Id, ShouldEqual, quiet.Id)
+			So(query.Result[1].AnnotationCount, ShouldEqual, 1)
+		})
+	})
+}
+
+func TestGetAlertsCreatedRecently(t *testing.T) {
+	Convey("Given alerts created at different times", t, func() {
+		InitTestDB(t)
+		defer resetTimeNow()
+
+		now := time.Now()
+		for i, age := range []time.Duration{2 * time.Hour, 20 * time.Minute, 5 * time.Minute} {
+			created := now.Add(-age)
+			timeNow = func() time.Time { return created }
+			_, err := insertTestAlert(fmt.Sprintf("alert %d", i), "", 1, insertTestDashboard(fmt.Sprintf("dash %d", i), 1, 0, false).Id, simplejson.New())
+			So(err, ShouldBeNil)
+		}
+		timeNow = func() time.Time { return now }
+
+		Convey("Should return the alerts created within the duration, newest first", func() {
+			query := &models.GetAlertsCreatedRecentlyQuery{OrgId: 1, Since: 30 * time.Minute}
+			So(GetAlertsCreatedRecently(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.Result[0].Name, ShouldEqual, "alert 2")
+			So(query.Result[1].Name, ShouldEqual, "alert 1")
+		})
+	})
+}
+
+func TestGetDatasourceAlertErrorRates(t *testing.T) {
+	Convey("Given alerts querying different data sources", t, func() {
+		InitTestDB(t)
+
+		flaky := &models.AddDataSourceCommand{OrgId: 1, Name: "flaky", Type: models.DS_GRAPHITE, Access: models.DS_ACCESS_PROXY, Url: "http://flaky"}
+		So(AddDataSource(flaky), ShouldBeNil)
+		stable := &models.AddDataSourceCommand{OrgId: 1, Name: "stable", Type: models.DS_GRAPHITE, Access: models.DS_ACCESS_PROXY, Url: "http://stable"}
+		So(AddDataSource(stable), ShouldBeNil)
+
+		settings := func(datasourceIds ...int64) *simplejson.Json {
+			conditions := make([]interface{}, 0)
+			for _, id := range datasourceIds {
+				conditions = append(conditions, map[string]interface{}{"query": map[string]interface{}{"datasourceId": id}})
+			}
+			alertSettings := simplejson.New()
+			alertSettings.Set("conditions", conditions)
+			return alertSettings
+		}
+
+		first, err := insertTestAlert("first", "", 1, insertTestDashboard("first", 1, 0, false).Id, settings(flaky.Result.Id))
+		So(err, ShouldBeNil)
+		_, err = insertTestAlert("second", "", 1, insertTestDashboard("second", 1, 0, false).Id, settings(flaky.Result.Id, stable.Result.Id, stable.Result.Id))
+		So(err, ShouldBeNil)
+
+		So(SetAlertState(&models.SetAlertStateCommand{AlertId: first.Id, OrgId: 1, State: models.AlertStateAlerting, Error: "timeout"}), ShouldBeNil)
+
+		Convey("Should count the alerts and erroring alerts per data source", func() {
+			query := &models.GetDatasourceAlertErrorRatesQuery{OrgId: 1}
+			So(GetDatasourceAlertErrorRates(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+
+			So(query.Result[0].DatasourceName, ShouldEqual, "flaky")
+			So(query.Result[0].AlertCount, ShouldEqual, 2)
+			So(query.Result[0].ErroringCount, ShouldEqual, 1)
+			So(query.Result[0].ErrorRate, ShouldEqual, 0.5)
+
+			So(query.Result[1].DatasourceName, ShouldEqual, "stable")
+			So(query.Result[1].AlertCount, ShouldEqual, 1)
+			So(query.Result[1].ErroringCount, ShouldEqual, 0)
+		})
+	})
+}
+
+func TestGetAlertsModifiedRecently(t *testing.T) {
+	Convey("Given alerts updated at different times", t, func() {
+		InitTestDB(t)
+		defer resetTimeNow()
+
+		now := time.Now()
+		for i, age := range []time.Duration{2 * time.Hour, 20 * time.Minute, 10 * time.Minute, 5 * time.Minute} {
+			updated := now.Add(-age)
+			timeNow = func() time.Time { return updated }
+			_, err := insertTestAlert(fmt.Sprintf("alert %d", i), "", 1, insertTestDashboard(fmt.Sprintf("dash %d", i), 1, 0, false).Id, simplejson.New())
+			So(err, ShouldBeNil)
+		}
+		timeNow = func() time.Time { return now }
+
+		Convey("Should return the most recently updated alerts up to the limit", func() {
+			query := &models.GetAlertsModifiedRecentlyQuery{OrgId: 1, Since: 30 * time.Minute, Limit: 2}
+			So(GetAlertsModifiedRecently(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.Result[0].Name, ShouldEqual, "alert 3")
+			So(query.Result[1].Name, ShouldEqual, "alert 2")
+			So(query.OldestUpdatedAt.Unix(), ShouldEqual, now.Add(-10*time.Minute).Unix())
+		})
+
+		Convey("Should return all alerts updated within the duration without a limit", func() {
+			query := &models.GetAlertsModifiedRecentlyQuery{OrgId: 1, Since: 30 * time.Minute}
+			So(GetAlertsModifiedRecently(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 3)
+			So(query.OldestUpdatedAt.Unix(), ShouldEqual, now.Add(-20*time.Minute).Unix())
+		})
+	})
+}
+
+func TestGetAlertsByEvalDataNull(t *testing.T) {
+	Convey("Given evaluated and new alerts", t, func() {
+		InitTestDB(t)
+
+		evaluated, err := insertTestAlert("evaluated", "", 1, insertTestDashboard("evaluated", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		fresh, err := insertTestAlert("new", "", 1, insertTestDashboard("new", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		paused, err := insertTestAlert("paused", "", 1, insertTestDashboard("paused", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+
+		evalData, _ := simplejson.NewJson([]byte(`{"evalMatches": []}`))
+		So(SetAlertState(&models.SetAlertStateCommand{AlertId: evaluated.Id, OrgId: 1, State: models.AlertStateOK, EvalData: evalData}), ShouldBeNil)
+		_, err = pauseAlert(1, paused.Id, true)
+		So(err, ShouldBeNil)
+
+		Convey("Should return the alerts without eval data", func() {
+			query := &models.GetAlertsByEvalDataNullQuery{OrgId: 1}
+			So(GetAlertsByEvalDataNull(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Id, ShouldEqual, fresh.Id)
+		})
+
+		Convey("Should include paused alerts when asked to", func() {
+			query := &models.GetAlertsByEvalDataNullQuery{OrgId: 1, IncludePaused: true}
+			So(GetAlertsByEvalDataNull(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.Result[1].Id, ShouldEqual, paused.Id)
+		})
+	})
+}
+
+func TestAlertsQuerySortBy(t *testing.T) {
+	Convey("Given alerts that changed state at different times", t, func() {
+		InitTestDB(t)
+		defer resetTimeNow()
+
+		now := time.Now()
+		states := []models.AlertStateType{models.AlertStateOK, models.AlertStateAlerting, models.AlertStateNoData}
+		for i, name := range []string{"a", "b", "c"} {
+			alert, err := insertTestAlert(name, "", 1, insertTestDashboard(name, 1, 0, false).Id, simplejson.New())
+			So(err, ShouldBeNil)
+
+			changed := now.Add(time.Duration(i) * time.Minute)
+			timeNow = func() time.Time { return changed }
+			So(SetAlertState(&models.SetAlertStateCommand{AlertId: alert.Id, OrgId: 1, State: states[i]}), ShouldBeNil)
+		}
+
+		admin := &models.SignedInUser{OrgRole: models.ROLE_ADMIN}
+		names := func(query *models.GetAlertsQuery) []string {
+			So(HandleAlertsQuery(query), ShouldBeNil)
+			result := make([]string, 0)
+			for _, alert := range query.Result {
+				result = append(result, alert.Name)
+			}
+			return result
+		}
+
+		Convey("Should sort by name by default", func() {
+			So(names(&models.GetAlertsQuery{OrgId: 1, User: admin}), ShouldResemble, []string{"a", "b", "c"})
+			So(names(&models.GetAlertsQuery{OrgId: 1, User: admin, SortDesc: true}), ShouldResemble, []string{"c", "b", "a"})
+		})
+
+		Convey("Should sort by the most recent state change", func() {
+			query := &models.GetAlertsQuery{OrgId: 1, User: admin, SortBy: "new_state_date", SortDesc: true}
+			So(names(query), ShouldResemble, []string{"c", "b", "a"})
+		})
+
+		Convey("Should sort by state", func() {
+			query := &models.GetAlertsQuery{OrgId: 1, User: admin, SortBy: "state"}
+			So(names(query), ShouldResemble, []string{"b", "c", "a"})
+		})
+
+		Convey("Should reject unknown columns", func() {
+			query := &models.GetAlertsQuery{OrgId: 1, User: admin, SortBy: "name; DROP TABLE alert"}
+			So(HandleAlertsQuery(query), ShouldEqual, models.ErrInvalidAlertSortBy)
+		})
+	})
+}
+
+func TestSaveAlertsPreserveState(t *testing.T) {
+	Convey("Given new alerts with a state", t, func() {
+		InitTestDB(t)
+
+		dash := insertTestDashboard("provisioned", 1, 0, false)
+		cmd := models.SaveAlertsCommand{
+			DashboardId: dash.Id,
+			OrgId:       1,
+			UserId:      1,
+			Alerts: []*models.Alert{
+				{PanelId: 1, DashboardId: dash.Id, OrgId: 1, Name: "alerting", Settings: simplejson.New(), State: models.AlertStateAlerting},
+				{PanelId: 2, DashboardId: dash.Id, OrgId: 1, Name: "invalid", Settings: simplejson.New(), State: "firing"},
+			},
+		}
+
+		Convey("Should reset the state without PreserveState", func() {
+			So(SaveAlerts(&cmd), ShouldBeNil)
+
+			alert, err := getAlertById(cmd.Alerts[0].Id)
+			So(err, ShouldBeNil)
+			So(alert.State, ShouldEqual, models.AlertStateUnknown)
+		})
+
+		Convey("Should keep valid states with PreserveState", func() {
+			cmd.PreserveState = true
+			So(SaveAlerts(&cmd), ShouldBeNil)
+
+			alert, err := getAlertById(cmd.Alerts[0].Id)
+			So(err, ShouldBeNil)
+			So(alert.State, ShouldEqual, models.AlertStateAlerting)
+
+			alert, err = getAlertById(cmd.Alerts[1].Id)
+			So(err, ShouldBeNil)
+			So(alert.State, ShouldEqual, models.AlertStateUnknown)
+		})
+	})
+}
+
+func TestGetAlertsByCreatedAt(t *testing.T) {
+	Convey("Given alerts created on different days", t, func() {
+		InitTestDB(t)
+		defer resetTimeNow()
+
+		day := time.Date(2019, 3, 14, 12, 0, 0, 0, time.UTC)
+		for i, created := range []time.Time{day.AddDate(0, 0, -1), day, day.Add(time.Hour), day.AddDate(0, 0, 1)} {
+			timeNow = func() time.Time { return created }
+			_, err := insertTestAlert(fmt.Sprintf("alert %d", i), "", 1, insertTestDashboard(fmt.Sprintf("dash %d", i), 1, 0, false).Id, simplejson.New())
+			So(err, ShouldBeNil)
+		}
+
+		Convey("Should return the alerts created on the day, ignoring the time of day", func() {
+			query := &models.GetAlertsByCreatedAtQuery{OrgId: 1, Date: day.Add(-11 * time.Hour)}
+			So(GetAlertsByCreatedAt(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.Result[0].Name, ShouldEqual, "alert 1")
+			So(query.Result[1].Name, ShouldEqual, "alert 2")
+		})
+	})
+}
+
+func TestSetAlertFrequencyByTier(t *testing.T) {
+	Convey("Given alerts saved from dashboard panels", t, func() {
+		InitTestDB(t)
+
+		critical, criticalAlert := insertTestAlertPanel("critical", 1, `{"name": "critical", "frequency": "1m"}`)
+		other, otherAlert := insertTestAlertPanel("other", 1, `{"name": "other", "frequency": "1m"}`)
+
+		cmd := &models.SetAlertFrequencyByTierCommand{
+			OrgId: 1,
+			Tiers: []*models.AlertFrequencyTier{
+				{Filter: models.AlertFilter{Query: "critical"}, Frequency: 10},
+				{Filter: models.AlertFilter{}, Frequency: 300},
+			},
+		}
+		So(SetAlertFrequencyByTier(cmd), ShouldBeNil)
+		So(cmd.Result, ShouldResemble, []int64{1, 1})
+
+		Convey("Should update the alert, its settings and its panel", func() {
+			alert, err := getAlertById(criticalAlert.Id)
+			So(err, ShouldBeNil)
+			So(alert.Frequency, ShouldEqual, 10)
+			So(alert.Settings.Get("frequency").MustString(), ShouldEqual, "10s")
+			So(getTestPanelAlert(critical.Id).Get("frequency").MustString(), ShouldEqual, "10s")
+
+			alert, err = getAlertById(otherAlert.Id)
+			So(err, ShouldBeNil)
+			So(alert.Frequency, ShouldEqual, 300)
+			So(getTestPanelAlert(other.Id).Get("frequency").MustString(), ShouldEqual, "300s")
+		})
+
+		Convey("Should save the dashboard as a new version", func() {
+			dash := getTestDashboard(critical.Id)
+			So(dash.Version, ShouldEqual, critical.Version+1)
+
+			versions := &models.GetDashboardVersionsQuery{OrgId: 1, DashboardId: critical.Id}
+			So(GetDashboardVersions(versions), ShouldBeNil)
+			So(versions.Result, ShouldHaveLength, 2)
+		})
+
+		Convey("Should reject frequencies below one second", func() {
+			cmd := &models.SetAlertFrequencyByTierCommand{OrgId: 1, Tiers: []*models.AlertFrequencyTier{{Frequency: 0}}}
+			So(SetAlertFrequencyByTier(cmd), ShouldEqual, models.ErrInvalidAlertFrequency)
+		})
+	})
+}
+
+func TestGetAlertsByForZero(t *testing.T) {
+	Convey("Given alerts with and without a for duration", t, func() {
+		InitTestDB(t)
+
+		_, err := insertTestAlert("zero", "", 1, insertTestDashboard("first", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		legacy, err := insertTestAlert("legacy", "", 1, insertTestDashboard("second", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		paused, err := insertTestAlert("paused", "", 1, insertTestDashboard("third", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		withFor, err := insertTestAlert("with for", "", 1, insertTestDashboard("fourth", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+
+		_, err = x.Exec("UPDATE alert SET "+dialect.Quote("for")+" = NULL WHERE id = ?", legacy.Id)
+		So(err, ShouldBeNil)
+		_, err = x.Exec("UPDATE alert SET "+dialect.Quote("for")+" = ? WHERE id = ?", int64(time.Minute), withFor.Id)
+		So(err, ShouldBeNil)
+		_, err = pauseAlert(1, paused.Id, true)
+		So(err, ShouldBeNil)
+
+		Convey("Should return the alerts without a for duration, including NULL", func() {
+			query := &models.GetAlertsByForZeroQuery{OrgId: 1}
+			So(GetAlertsByForZero(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.Result[0].Name, ShouldEqual, "legacy")
+			So(query.Result[1].Name, ShouldEqual, "zero")
+		})
+
+		Convey("Should include paused alerts when asked to", func() {
+			query := &models.GetAlertsByForZeroQuery{OrgId: 1, IncludePaused: true}
+			So(GetAlertsByForZero(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 3)
+		})
+	})
+}
+
+func TestGetAlertDependencyCounts(t *testing.T) {
+	Convey("Given alerts with annotations, tags and notification states", t, func() {
+		InitTestDB(t)
+
+		tagged, _ := simplejson.NewJson([]byte(`{"alertRuleTags": {"env": "prod", "team": "sre"}}`))
+		busy, err := insertTestAlert("busy", "", 1, insertTestDashboard("busy", 1, 0, false).Id, tagged)
+		So(err, ShouldBeNil)
+		idle, err := insertTestAlert("idle", "", 1, insertTestDashboard("idle", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		otherOrg, err := insertTestAlert("other org", "", 2, insertTestDashboard("other org", 2, 0, false).Id, tagged)
+		So(err, ShouldBeNil)
+
+		repo := SqlAnnotationRepo{}
+		for i := 0; i < 3; i++ {
+			So(repo.Save(&annotations.Item{OrgId: 1, AlertId: busy.Id, NewState: "alerting", Epoch: int64(i)}), ShouldBeNil)
+		}
+		for _, notifierId := range []int64{1, 2} {
+			state := &models.GetOrCreateNotificationStateQuery{OrgId: 1, AlertId: busy.Id, NotifierId: notifierId}
+			So(GetOrCreateAlertNotificationState(context.Background(), state), ShouldBeNil)
+		}
+
+		Convey("Should count the dependent rows of the alerts of the org", func() {
+			query := &models.GetAlertDependencyCountsQuery{OrgId: 1, AlertIds: []int64{idle.Id, busy.Id, otherOrg.Id}}
+			So(GetAlertDependencyCounts(query), ShouldBeNil)
+			So(query.Result, ShouldResemble, []*models.AlertDependencyCountsDTO{
+				{AlertId: busy.Id, Annotations: 3, AlertRuleTags: 2, NotificationStates: 2},
+				{AlertId: idle.Id},
+			})
+		})
+
+		Convey("Should return no counts without alert ids", func() {
+			query := &models.GetAlertDependencyCountsQuery{OrgId: 1}
+			So(GetAlertDependencyCounts(query), ShouldBeNil)
+			So(query.Result, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestGetAlertsByConditionQueryParams(t *testing.T) {
+	Convey("Given alerts querying different refIds and time ranges", t, func() {
+		InitTestDB(t)
+
+		fiveMinutes, _ := simplejson.NewJson([]byte(`{"conditions": [{"query": {"params": ["A", "5m", "now"]}}]}`))
+		tenMinutes, _ := simplejson.NewJson([]byte(`{"conditions": [{"query": {"params": ["B", "10m", "now"]}}, {"query": {"params": ["A", "5m", "now"]}}]}`))
+		oneHour, _ := simplejson.NewJson([]byte(`{"conditions": [{"query": {"params": ["A", "1h", "now"]}}]}`))
+
+		_, err := insertTestAlert("five minutes", "", 1, insertTestDashboard("first", 1, 0, false).Id, fiveMinutes)
+		So(err, ShouldBeNil)
+		_, err = insertTestAlert("ten minutes", "", 1, insertTestDashboard("second", 1, 0, false).Id, tenMinutes)
+		So(err, ShouldBeNil)
+		_, err = insertTestAlert("one hour", "", 1, insertTestDashboard("third", 1, 0, false).Id, oneHour)
+		So(err, ShouldBeNil)
+		_, err = insertTestAlert("other org", "", 2, insertTestDashboard("fourth", 2, 0, false).Id, fiveMinutes)
+		So(err, ShouldBeNil)
+
+		Convey("Should return the alerts with any condition matching the param", func() {
+			query := &models.GetAlertsByConditionQueryParamsQuery{OrgId: 1, ParamIndex: 1, ParamValue: "5m"}
+			So(GetAlertsByConditionQueryParams(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.Result[0].Name, ShouldEqual, "five minutes")
+			So(query.Result[1].Name, ShouldEqual, "ten minutes")
+		})
+
+		Convey("Should return nothing for an index beyond the params", func() {
+			query := &models.GetAlertsByConditionQueryParamsQuery{OrgId: 1, ParamIndex: 3, ParamValue: "now"}
+			So(GetAlertsByConditionQueryParams(query), ShouldBeNil)
+			So(query.Result, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestGetAlertsByEvalDataSize(t *testing.T) {
+	Convey("Given alerts with eval data of different sizes", t, func() {
+		InitTestDB(t)
+
+		small, err := insertTestAlert("small", "", 1, insertTestDashboard("small", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		large, err := insertTestAlert("large", "", 1, insertTestDashboard("large", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+
+		// multi byte characters make the size in bytes differ from the
+		// length in characters
+		smallData := simplejson.NewFromAny(map[string]interface{}{"error": "é"})
+		largeData := simplejson.NewFromAny(map[string]interface{}{"error": "ééééééééééééééééééééé"})
+		So(SetAlertState(&models.SetAlertStateCommand{AlertId: small.Id, OrgId: 1, State: models.AlertStateAlerting, EvalData: smallData}), ShouldBeNil)
+		So(SetAlertState(&models.SetAlertStateCommand{AlertId: large.Id, OrgId: 1, State: models.AlertStateAlerting, EvalData: largeData}), ShouldBeNil)
+
+		largeBytes, err := largeData.Encode()
+		So(err, ShouldBeNil)
+
+		Convey("Should return the alerts with at least the given size in bytes, largest first", func() {
+			query := &models.GetAlertsByEvalDataSizeQuery{OrgId: 1, MinBytes: 1}
+			So(GetAlertsByEvalDataSize(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.Result[0].Id, ShouldEqual, large.Id)
+			So(query.Result[0].EvalDataBytes, ShouldEqual, len(largeBytes))
+			So(query.Result[1].Id, ShouldEqual, small.Id)
+		})
+
+		Convey("Should count bytes rather than characters", func() {
+			query := &models.GetAlertsByEvalDataSizeQuery{OrgId: 1, MinBytes: int64(len(largeBytes))}
+			So(GetAlertsByEvalDataSize(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Id, ShouldEqual, large.Id)
+		})
+	})
+}
+
+func TestGetAlertsByNotificationType(t *testing.T) {
+	Convey("Given alerts sending to channels of different types", t, func() {
+		InitTestDB(t)
+
+		slack := &models.CreateAlertNotificationCommand{Uid: "slack", Name: "Slack", Type: "slack", OrgId: 1, Settings: simplejson.New()}
+		So(CreateAlertNotificationCommand(slack), ShouldBeNil)
+		email := &models.CreateAlertNotificationCommand{Uid: "email", Name: "Email", Type: "email", OrgId: 1, Settings: simplejson.New()}
+		So(CreateAlertNotificationCommand(email), ShouldBeNil)
+
+		byId, _ := simplejson.NewJson([]byte(fmt.Sprintf(`{"notifications": [{"id": %d}]}`, slack.Result.Id)))
+		byUid, _ := simplejson.NewJson([]byte(`{"notifications": [{"uid": "email"}, {"uid": "slack"}]}`))
+		emailOnly, _ := simplejson.NewJson([]byte(`{"notifications": [{"uid": "email"}]}`))
+
+		_, err := insertTestAlert("by id", "", 1, insertTestDashboard("first", 1, 0, false).Id, byId)
+		So(err, ShouldBeNil)
+		_, err = insertTestAlert("by uid", "", 1, insertTestDashboard("second", 1, 0, false).Id, byUid)
+		So(err, ShouldBeNil)
+		_, err = insertTestAlert("email only", "", 1, insertTestDashboard("third", 1, 0, false).Id, emailOnly)
+		So(err, ShouldBeNil)
+
+		Convey("Should return the alerts sending to a channel of the type", func() {
+			query := &models.GetAlertsByNotificationTypeQuery{OrgId: 1, Type: "slack"}
+			So(GetAlertsByNotificationType(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.Result[0].Name, ShouldEqual, "by id")
+			So(query.Result[1].Name, ShouldEqual, "by uid")
+		})
+
+		Convey("Should return nothing for a type without channels", func() {
+			query := &models.GetAlertsByNotificationTypeQuery{OrgId: 1, Type: "pagerduty"}
+			So(GetAlertsByNotificationType(query), ShouldBeNil)
+			So(query.Result, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestGetAlertsByExecutionErrorType(t *testing.T) {
+	Convey("Given alerts failing with different execution errors", t, func() {
+		InitTestDB(t)
+
+		executionErrors := map[string]string{
+			"deadline": "request failed: context deadline exceeded",
+			"io":       "read tcp: i/o timeout",
+			"refused":  "dial tcp 10.0.0.1:9090: connect: connection refused",
+			"healthy":  "",
+		}
+		for name, executionError := range executionErrors {
+			alert, err := insertTestAlert(name, "", 1, insertTestDashboard(name, 1, 0, false).Id, simplejson.New())
+			So(err, ShouldBeNil)
+			So(SetAlertState(&models.SetAlertStateCommand{AlertId: alert.Id, OrgId: 1, State: models.AlertStateAlerting, Error: executionError}), ShouldBeNil)
+		}
+
+		Convey("Should group the failing alerts by category, largest group first", func() {
+			query := &models.GetAlertsByExecutionErrorTypeQuery{OrgId: 1}
+			So(GetAlertsByExecutionErrorType(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.Result[0].Category, ShouldEqual, models.ExecutionErrorCategoryTimeout)
+			So(query.Result[0].Count, ShouldEqual, 2)
+			So(query.Result[0].Alerts[0].Name, ShouldEqual, "deadline")
+			So(query.Result[0].Alerts[1].Name, ShouldEqual, "io")
+			So(query.Result[1].Category, ShouldEqual, models.ExecutionErrorCategoryConnection)
+			So(query.Result[1].Count, ShouldEqual, 1)
+			So(query.Result[1].ExampleError, ShouldEqual, executionErrors["refused"])
+		})
+
+		Convey("Should only group errors matching the pattern", func() {
+			query := &models.GetAlertsByExecutionErrorTypeQuery{OrgId: 1, ErrorPattern: "tcp"}
+			So(GetAlertsByExecutionErrorType(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.Result[0].Count, ShouldEqual, 1)
+			So(query.Result[1].Count, ShouldEqual, 1)
+		})
+	})
+}
+
+func TestAlertsQueryNoSeriesFilter(t *testing.T) {
+	Convey("Given alerts whose last evaluation found and did not find series", t, func() {
+		InitTestDB(t)
+
+		noData, err := insertTestAlert("no data", "", 1, insertTestDashboard("first", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		withData, err := insertTestAlert("with data", "", 1, insertTestDashboard("second", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		_, err = insertTestAlert("never evaluated", "", 1, insertTestDashboard("third", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+
+		noDataEval := simplejson.NewFromAny(map[string]interface{}{"noData": true})
+		matchesEval := simplejson.NewFromAny(map[string]interface{}{"evalMatches": []interface{}{map[string]interface{}{"metric": "cpu", "value": 90}}})
+		So(SetAlertState(&models.SetAlertStateCommand{AlertId: noData.Id, OrgId: 1, State: models.AlertStateNoData, EvalData: noDataEval}), ShouldBeNil)
+		So(SetAlertState(&models.SetAlertStateCommand{AlertId: withData.Id, OrgId: 1, State: models.AlertStateAlerting, EvalData: matchesEval}), ShouldBeNil)
+
+		Convey("Should only return the alerts whose last state change found no series", func() {
+			query := &models.GetAlertsQuery{OrgId: 1, User: &models.SignedInUser{OrgRole: models.ROLE_ADMIN}, NoSeries: true}
+			So(HandleAlertsQuery(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Id, ShouldEqual, noData.Id)
+		})
+	})
+}
+
+func TestGetOldestFiringAlert(t *testing.T) {
+	Convey("Given alerts that started firing at different times", t, func() {
+		InitTestDB(t)
+		defer resetTimeNow()
+
+		now := time.Date(2019, 3, 14, 12, 0, 0, 0, time.UTC)
+		oldest, err := insertTestAlert("oldest", "", 1, insertTestDashboard("first", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		newest, err := insertTestAlert("newest", "", 1, insertTestDashboard("second", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		ok, err := insertTestAlert("ok", "", 2, insertTestDashboard("third", 2, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+
+		setState := func(alertId int64, state models.AlertStateType, at time.Time) {
+			timeNow = func() time.Time { return at }
+			So(SetAlertState(&models.SetAlertStateCommand{AlertId: alertId, OrgId: 1, State: state}), ShouldBeNil)
+		}
+		setState(ok.Id, models.AlertStateOK, now.Add(-3*time.Hour))
+		setState(oldest.Id, models.AlertStateAlerting, now.Add(-2*time.Hour))
+		setState(newest.Id, models.AlertStateAlerting, now.Add(-time.Hour))
+		timeNow = func() time.Time { return now }
+
+		Convey("Should return the alert firing the longest and for how long", func() {
+			query := &models.GetOldestFiringAlertQuery{OrgId: 1}
+			So(GetOldestFiringAlert(query), ShouldBeNil)
+			So(query.Result, ShouldNotBeNil)
+			So(query.Result.Id, ShouldEqual, oldest.Id)
+			So(query.FiringDuration, ShouldEqual, 2*time.Hour)
+		})
+
+		Convey("Should return nil when no alert of the org is firing", func() {
+			query := &models.GetOldestFiringAlertQuery{OrgId: 2}
+			So(GetOldestFiringAlert(query), ShouldBeNil)
+			So(query.Result, ShouldBeNil)
+			So(query.FiringDuration, ShouldEqual, 0)
+		})
+	})
+}
+
+func TestGetAlertsByNotificationCount(t *testing.T) {
+	Convey("Given alerts sending to different numbers of channels", t, func() {
+		InitTestDB(t)
+
+		one, _ := simplejson.NewJson([]byte(`{"notifications": [{"uid": "ops"}]}`))
+		three, _ := simplejson.NewJson([]byte(`{"notifications": [{"uid": "ops"}, {"uid": "dev"}, {"id": 3}]}`))
+
+		_, err := insertTestAlert("silent", "", 1, insertTestDashboard("first", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		_, err = insertTestAlert("one", "", 1, insertTestDashboard("second", 1, 0, false).Id, one)
+		So(err, ShouldBeNil)
+		_, err = insertTestAlert("three", "", 1, insertTestDashboard("third", 1, 0, false).Id, three)
+		So(err, ShouldBeNil)
+
+		Convey("Should return the alerts within the inclusive range with their counts", func() {
+			query := &models.GetAlertsByNotificationCountQuery{OrgId: 1, MinNotifications: 1, MaxNotifications: 3}
+			So(GetAlertsByNotificationCount(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.Result[0].Name, ShouldEqual, "one")
+			So(query.Result[0].NotificationCount, ShouldEqual, 1)
+			So(query.Result[1].Name, ShouldEqual, "three")
+			So(query.Result[1].NotificationCount, ShouldEqual, 3)
+		})
+
+		Convey("Should return the alerts notifying nobody", func() {
+			query := &models.GetAlertsByNotificationCountQuery{OrgId: 1}
+			So(GetAlertsByNotificationCount(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Name, ShouldEqual, "silent")
+			So(query.Result[0].NotificationCount, ShouldEqual, 0)
+		})
+	})
+}
+
+func TestGetAlertsBeyondFrequencyCap(t *testing.T) {
+	Convey("Given alerts with different frequencies", t, func() {
+		InitTestDB(t)
+
+		for name, frequency := range map[string]int64{"fast": 10, "at cap": 30, "slow": 300, "unset": 0} {
+			alert, err := insertTestAlert(name, "", 1, insertTestDashboard(name, 1, 0, false).Id, simplejson.New())
+			So(err, ShouldBeNil)
+			_, err = x.Exec("UPDATE alert SET frequency = ? WHERE id = ?", frequency, alert.Id)
+			So(err, ShouldBeNil)
+		}
+
+		Convey("Should return the alerts faster than the cap, leaving out alerts without a frequency", func() {
+			query := &models.GetAlertsBeyondFrequencyCapQuery{OrgId: 1, FrequencyCap: 30}
+			So(GetAlertsBeyondFrequencyCap(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Name, ShouldEqual, "fast")
+		})
+	})
+}
+
+func TestGetAlertsByForDurationDistribution(t *testing.T) {
+	Convey("Given alerts with different for durations", t, func() {
+		InitTestDB(t)
+
+		durations := []time.Duration{0, time.Minute, 5 * time.Minute, 5 * time.Minute, 6 * time.Minute}
+		for i, forDuration := range durations {
+			alert, err := insertTestAlert(fmt.Sprintf("alert %d", i), "", 1, insertTestDashboard(fmt.Sprintf("dash %d", i), 1, 0, false).Id, simplejson.New())
+			So(err, ShouldBeNil)
+			_, err = x.Exec("UPDATE alert SET "+dialect.Quote("for")+" = ? WHERE id = ?", int64(forDuration), alert.Id)
+			So(err, ShouldBeNil)
+		}
+
+		Convey("Should count the alerts at or below each bound, sorted by bound", func() {
+			query := &models.GetAlertsByForDurationDistributionQuery{
+				OrgId:   1,
+				Buckets: []time.Duration{5 * time.Minute, time.Minute, 10 * time.Minute},
+			}
+			So(GetAlertsByForDurationDistribution(query), ShouldBeNil)
+			So(query.Result, ShouldResemble, []*models.ForDurationBucket{
+				{UpperBound: time.Minute, AlertCount: 2},
+				{UpperBound: 5 * time.Minute, AlertCount: 4},
+				{UpperBound: 10 * time.Minute, AlertCount: 5},
+			})
+		})
+
+		Convey("Should count alerts without a for duration in a zero bucket", func() {
+			query := &models.GetAlertsByForDurationDistributionQuery{OrgId: 1, Buckets: []time.Duration{0}}
+			So(GetAlertsByForDurationDistribution(query), ShouldBeNil)
+			So(query.Result, ShouldResemble, []*models.ForDurationBucket{{UpperBound: 0, AlertCount: 1}})
+		})
+	})
+}
+
+func TestGetAlertsByNameLength(t *testing.T) {
+	Convey("Given alerts with names of different lengths", t, func() {
+		InitTestDB(t)
+
+		names := []string{"cpu", "memory", "éééééééééé", "disk usage"}
+		for i, name := range names {
+			_, err := insertTestAlert(name, "", 1, insertTestDashboard(fmt.Sprintf("dash %d", i), 1, 0, false).Id, simplejson.New())
+			So(err, ShouldBeNil)
+		}
+
+		Convey("Should return the alerts within the inclusive range counting characters, longest first", func() {
+			query := &models.GetAlertsByNameLengthQuery{OrgId: 1, MinLength: 6, MaxLength: 10}
+			So(GetAlertsByNameLength(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 3)
+			So(query.Result[0].Name, ShouldEqual, "disk usage")
+			So(query.Result[0].NameLength, ShouldEqual, 10)
+			So(query.Result[1].Name, ShouldEqual, "éééééééééé")
+			So(query.Result[1].NameLength, ShouldEqual, 10)
+			So(query.Result[2].Name, ShouldEqual, "memory")
+			So(query.Result[2].SuggestedName, ShouldEqual, "")
+		})
+
+		// only SQLite stores names longer than the name column
+		if dialect.DriverName() == migrator.SQLITE {
+			Convey("Should suggest a truncated name for names longer than the maximum", func() {
+				long := strings.Repeat("a", models.AlertNameMaxLength+10)
+				_, err := insertTestAlert(long, "", 1, insertTestDashboard("long", 1, 0, false).Id, simplejson.New())
+				So(err, ShouldBeNil)
+
+				query := &models.GetAlertsByNameLengthQuery{OrgId: 1, MinLength: models.AlertNameMaxLength + 1, MaxLength: 1000}
+				So(GetAlertsByNameLength(query), ShouldBeNil)
+				So(query.Result, ShouldHaveLength, 1)
+				So(query.Result[0].NameLength, ShouldEqual, models.AlertNameMaxLength+10)
+				So(query.Result[0].SuggestedName, ShouldEqual, long[:models.AlertNameMaxLength])
+			})
+		}
+	})
+}
+
+func TestGetAlertsByStateChangeSince(t *testing.T) {
+	Convey("Given alerts that changed state at different times", t, func() {
+		InitTestDB(t)
+		defer resetTimeNow()
+
+		now := time.Date(2019, 3, 14, 12, 0, 0, 0, time.UTC)
+		timeNow = func() time.Time { return now.Add(-24 * time.Hour) }
+
+		changes := []struct {
+			name  string
+			state models.AlertStateType
+			at    time.Time
+		}{
+			{"stale", models.AlertStateAlerting, now.Add(-2 * time.Hour)},
+			{"recovered", models.AlertStateOK, now.Add(-30 * time.Minute)},
+			{"firing", models.AlertStateAlerting, now.Add(-10 * time.Minute)},
+		}
+		for _, change := range changes {
+			alert, err := insertTestAlert(change.name, "", 1, insertTestDashboard(change.name, 1, 0, false).Id, simplejson.New())
+			So(err, ShouldBeNil)
+
+			at := change.at
+			timeNow = func() time.Time { return at }
+			So(SetAlertState(&models.SetAlertStateCommand{AlertId: alert.Id, OrgId: 1, State: change.state}), ShouldBeNil)
+			timeNow = func() time.Time { return now.Add(-24 * time.Hour) }
+		}
+		_, err := insertTestAlert("unchanged", "", 1, insertTestDashboard("unchanged", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+
+		Convey("Should return the alerts that changed state since, most recent first", func() {
+			query := &models.GetAlertsByStateChangeSinceQuery{OrgId: 1, Since: now.Add(-time.Hour)}
+			So(GetAlertsByStateChangeSince(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.Result[0].Name, ShouldEqual, "firing")
+			So(query.Result[1].Name, ShouldEqual, "recovered")
+		})
+
+		Convey("Should filter by state and limit the result", func() {
+			query := &models.GetAlertsByStateChangeSinceQuery{OrgId: 1, Since: now.Add(-3 * time.Hour), States: []models.AlertStateType{models.AlertStateAlerting}, Limit: 1}
+			So(GetAlertsByStateChangeSince(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Name, ShouldEqual, "firing")
+		})
+	})
+}
+
+func TestGetAlertsByMultipleDashboardUids(t *testing.T) {
+	Convey("Given alerts on several dashboards", t, func() {
+		InitTestDB(t)
+
+		first := insertTestDashboard("first", 1, 0, false)
+		second := insertTestDashboard("second", 1, 0, false)
+		empty := insertTestDashboard("empty", 1, 0, false)
+		otherOrg := insertTestDashboard("other org", 2, 0, false)
+
+		cpu, err := insertTestAlert("cpu", "", 1, first.Id, simplejson.New())
+		So(err, ShouldBeNil)
+		memory, err := insertTestAlert("memory", "", 1, second.Id, simplejson.New())
+		So(err, ShouldBeNil)
+		_, err = insertTestAlert("other org", "", 2, otherOrg.Id, simplejson.New())
+		So(err, ShouldBeNil)
+
+		Convey("Should group the alerts by dashboard uid and report unresolved uids", func() {
+			query := &models.GetAlertsByMultipleDashboardUidsQuery{
+				OrgId:         1,
+				DashboardUIDs: []string{first.Uid, second.Uid, empty.Uid, otherOrg.Uid, "missing"},
+			}
+			So(GetAlertsByMultipleDashboardUids(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 3)
+			So(query.Result[first.Uid], ShouldHaveLength, 1)
+			So(query.Result[first.Uid][0].Id, ShouldEqual, cpu.Id)
+			So(query.Result[second.Uid], ShouldHaveLength, 1)
+			So(query.Result[second.Uid][0].Id, ShouldEqual, memory.Id)
+			So(query.Result[empty.Uid], ShouldBeEmpty)
+			So(query.UnresolvedUIDs, ShouldResemble, []string{otherOrg.Uid, "missing"})
+		})
+	})
+}
+
+func TestGetAlertingCapacityForecast(t *testing.T) {
+	Convey("Given alerts with different frequencies", t, func() {
+		InitTestDB(t)
+
+		timeNow = func() time.Time { return time.Unix(1599999960, 0) }
+		defer resetTimeNow()
+
+		minInterval := setting.AlertingMinInterval
+		setting.AlertingMinInterval = 30
+		defer func() { setting.AlertingMinInterval = minInterval }()
+
+		often, err := insertTestAlert("often", "", 1, insertTestDashboard("often", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		belowMin, err := insertTestAlert("below min interval", "", 1, insertTestDashboard("below min interval", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		paused, err := insertTestAlert("paused", "", 1, insertTestDashboard("paused", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+
+		_, err = x.Exec("UPDATE alert SET frequency = ? WHERE id IN (?, ?)", 10, often.Id, paused.Id)
+		So(err, ShouldBeNil)
+		_, err = x.Exec("UPDATE alert SET frequency = ? WHERE id = ?", 1, belowMin.Id)
+		So(err, ShouldBeNil)
+		_, err = pauseAlert(1, paused.Id, true)
+		So(err, ShouldBeNil)
+
+		Convey("Should clamp to the min interval and skip paused alerts", func() {
+			query := &models.GetAlertingCapacityForecastQuery{
+				OrgId:             1,
+				ForecastIntervals: []time.Duration{time.Minute, 0},
+			}
+			So(GetAlertingCapacityForecast(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+
+			// 6 evaluations every 10s and 2 clamped to every 30s
+			So(query.Result[0].Interval, ShouldEqual, time.Minute)
+			So(query.Result[0].EstimatedEvalsPerSecond, ShouldAlmostEqual, 8.0/60.0)
+			So(query.Result[1].EstimatedEvalsPerSecond, ShouldEqual, 0)
+		})
+	})
+}
+
+func TestGetAlertsByStateAndAge(t *testing.T) {
+	Convey("Given alerts that changed state at different times", t, func() {
+		InitTestDB(t)
+		defer resetTimeNow()
+
+		now := time.Date(2019, 3, 14, 12, 0, 0, 0, time.UTC)
+		stale, err := insertTestAlert("stale", "", 1, insertTestDashboard("stale", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		fresh, err := insertTestAlert("fresh", "", 1, insertTestDashboard("fresh", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		staleOk, err := insertTestAlert("stale ok", "", 1, insertTestDashboard("stale ok", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+
+		setState := func(alertId int64, state models.AlertStateType, at time.Time) {
+			timeNow = func() time.Time { return at }
+			So(SetAlertState(&models.SetAlertStateCommand{AlertId: alertId, OrgId: 1, State: state}), ShouldBeNil)
+		}
+		setState(stale.Id, models.AlertStateNoData, now.Add(-48*time.Hour))
+		setState(fresh.Id, models.AlertStateNoData, now.Add(-time.Hour))
+		setState(staleOk.Id, models.AlertStateOK, now.Add(-48*time.Hour))
+		timeNow = func() time.Time { return now }
+
+		stateOf := func(alertId int64) models.AlertStateType {
+			alert, err := getAlertById(alertId)
+			So(err, ShouldBeNil)
+			return alert.State
+		}
+
+		query := &models.GetAlertsByStateAndAgeQuery{
+			OrgId:     1,
+			State:     models.AlertStateNoData,
+			OlderThan: 24 * time.Hour,
+		}
+
+		Convey("Should find the alerts in the state for longer than the age", func() {
+			So(GetAlertsByStateAndAge(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Id, ShouldEqual, stale.Id)
+			So(stateOf(stale.Id), ShouldEqual, models.AlertStateNoData)
+		})
+
+		Convey("Should pause the alerts found with auto pause", func() {
+			query.AutoPause = true
+			So(GetAlertsByStateAndAge(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].State, ShouldEqual, models.AlertStateNoData)
+			So(stateOf(stale.Id), ShouldEqual, models.AlertStatePaused)
+			So(stateOf(fresh.Id), ShouldEqual, models.AlertStateNoData)
+			So(stateOf(staleOk.Id), ShouldEqual, models.AlertStateOK)
+		})
+	})
+}
+
+func TestGetAlertsForChannelGroup(t *testing.T) {
+	Convey("Given alerts sending to different channels", t, func() {
+		InitTestDB(t)
+
+		ops := &models.CreateAlertNotificationCommand{Uid: "ops", Name: "Ops", Type: "email", OrgId: 1, Settings: simplejson.New()}
+		So(CreateAlertNotificationCommand(ops), ShouldBeNil)
+		dev := &models.CreateAlertNotificationCommand{Uid: "dev", Name: "Dev", Type: "email", OrgId: 1, Settings: simplejson.New()}
+		So(CreateAlertNotificationCommand(dev), ShouldBeNil)
+		other := &models.CreateAlertNotificationCommand{Uid: "other", Name: "Other", Type: "email", OrgId: 1, Settings: simplejson.New()}
+		So(CreateAlertNotificationCommand(other), ShouldBeNil)
+
+		toOps, _ := simplejson.NewJson([]byte(`{"notifications": [{"uid": "ops"}]}`))
+		toDevById, _ := simplejson.NewJson([]byte(fmt.Sprintf(`{"notifications": [{"id": %d}, {"uid": "other"}]}`, dev.Result.Id)))
+		toOther, _ := simplejson.NewJson([]byte(`{"notifications": [{"uid": "other"}]}`))
+
+		opsAlert, err := insertTestAlert("a ops", "", 1, insertTestDashboard("a ops", 1, 0, false).Id, toOps)
+		So(err, ShouldBeNil)
+		devAlert, err := insertTestAlert("b dev", "", 1, insertTestDashboard("b dev", 1, 0, false).Id, toDevById)
+		So(err, ShouldBeNil)
+		_, err = insertTestAlert("c other", "", 1, insertTestDashboard("c other", 1, 0, false).Id, toOther)
+		So(err, ShouldBeNil)
+		_, err = insertTestAlert("d silent", "", 1, insertTestDashboard("d silent", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+
+		Convey("Should find the alerts sending to any channel of the group", func() {
+			query := &models.GetAlertsForChannelGroupQuery{OrgId: 1, ChannelUids: []string{"ops", "dev"}}
+			So(GetAlertsForChannelGroup(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.Result[0].Id, ShouldEqual, opsAlert.Id)
+			So(query.Result[0].ChannelUids, ShouldResemble, []string{"ops"})
+			So(query.Result[1].Id, ShouldEqual, devAlert.Id)
+			So(query.Result[1].ChannelUids, ShouldResemble, []string{"dev"})
+		})
+
+		Convey("Should find nothing for an empty group", func() {
+			query := &models.GetAlertsForChannelGroupQuery{OrgId: 1}
+			So(GetAlertsForChannelGroup(query), ShouldBeNil)
+			So(query.Result, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestGetAlertsByPanel(t *testing.T) {
+	Convey("Given alerts on the same panel of several dashboards", t, func() {
+		InitTestDB(t)
+
+		first := insertTestDashboard("first", 1, 0, false)
+		second := insertTestDashboard("second", 1, 0, false)
+		otherOrg := insertTestDashboard("other org", 2, 0, false)
+
+		onFirst := insertTestPanelAlerts(1, first.Id, "cpu", "memory")[0]
+		onSecond := insertTestPanelAlerts(1, second.Id, "cpu")[0]
+		insertTestPanelAlerts(2, otherOrg.Id, "cpu")
+
+		Convey("Should find the alerts on the panel of the org ordered by dashboard", func() {
+			query := &models.GetAlertsByPanelQuery{OrgId: 1, PanelId: 1}
+			So(GetAlertsByPanel(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.Result[0].Id, ShouldEqual, onFirst.Id)
+			So(query.Result[1].Id, ShouldEqual, onSecond.Id)
+		})
+
+		Convey("Should find nothing for an unknown panel", func() {
+			query := &models.GetAlertsByPanelQuery{OrgId: 1, PanelId: 3}
+			So(GetAlertsByPanel(query), ShouldBeNil)
+			So(query.Result, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestGetAlertsWithSharedPanelId(t *testing.T) {
+	Convey("Given alerts sharing panel ids across dashboards", t, func() {
+		InitTestDB(t)
+
+		first := insertTestDashboard("first", 1, 0, false)
+		second := insertTestDashboard("second", 1, 0, false)
+		otherOrg := insertTestDashboard("other org", 2, 0, false)
+
+		onFirst := insertTestPanelAlerts(1, first.Id, "cpu", "memory")[0]
+		onSecond := insertTestPanelAlerts(1, second.Id, "cpu")[0]
+		insertTestPanelAlerts(2, otherOrg.Id, "cpu")
+
+		Convey("Should only group panel ids used on more than one dashboard", func() {
+			query := &models.GetAlertsWithSharedPanelIdQuery{OrgId: 1}
+			So(GetAlertsWithSharedPanelId(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].PanelId, ShouldEqual, 1)
+			So(query.Result[0].Alerts, ShouldHaveLength, 2)
+			So(query.Result[0].Alerts[0].Id, ShouldEqual, onFirst.Id)
+			So(query.Result[0].Alerts[1].Id, ShouldEqual, onSecond.Id)
+		})
+
+		Convey("Should find nothing when no panel id is shared", func() {
+			query := &models.GetAlertsWithSharedPanelIdQuery{OrgId: 2}
+			So(GetAlertsWithSharedPanelId(query), ShouldBeNil)
+			So(query.Result, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestGetEvaluationSchedulePreview(t *testing.T) {
+	Convey("Given alerts with different frequencies", t, func() {
+		InitTestDB(t)
+
+		timeNow = func() time.Time { return time.Unix(1600000000, 0) }
+		defer resetTimeNow()
+
+		minInterval := setting.AlertingMinInterval
+		setting.AlertingMinInterval = 1
+		defer func() { setting.AlertingMinInterval = minInterval }()
+
+		setFrequency := func(name string, frequency int64) *models.Alert {
+			alert, err := insertTestAlert(name, "", 1, insertTestDashboard(name, 1, 0, false).Id, simplejson.New())
+			So(err, ShouldBeNil)
+			_, err = x.Exec("UPDATE alert SET frequency = ? WHERE id = ?", frequency, alert.Id)
+			So(err, ShouldBeNil)
+			return alert
+		}
+		often := setFrequency("often", 10)
+		unset := setFrequency("no frequency", 0)
+		slow := setFrequency("slow", 50)
+		setFrequency("hourly", 3600)
+		paused := setFrequency("paused", 10)
+		_, err := pauseAlert(1, paused.Id, true)
+		So(err, ShouldBeNil)
+
+		Convey("Should treat a missing frequency as every minute", func() {
+			query := &models.GetEvaluationSchedulePreviewQuery{OrgId: 1, Window: 30 * time.Second}
+			So(GetEvaluationSchedulePreview(query), ShouldBeNil)
+			So(query.DueAlertIds, ShouldResemble, []int64{often.Id, unset.Id})
+			So(query.DueCount, ShouldEqual, 2)
+		})
+
+		Convey("Should default to a window of one minute", func() {
+			query := &models.GetEvaluationSchedulePreviewQuery{OrgId: 1}
+			So(GetEvaluationSchedulePreview(query), ShouldBeNil)
+			So(query.DueAlertIds, ShouldResemble, []int64{often.Id, unset.Id, slow.Id})
+			So(query.DueCount, ShouldEqual, 3)
+		})
+	})
+}
+
+func TestGetOrgsWithAlertStateCountAboveThreshold(t *testing.T) {
+	Convey("Given orgs with different numbers of firing alerts", t, func() {
+		InitTestDB(t)
+
+		dashboards := 0
+		insertWithState := func(orgId int64, state models.AlertStateType) {
+			dashboards++
+			dash := insertTestDashboard(fmt.Sprintf("dash %d", dashboards), orgId, 0, false)
+			alert, err := insertTestAlert("alert", "", orgId, dash.Id, simplejson.New())
+			So(err, ShouldBeNil)
+			_, err = x.Exec("UPDATE alert SET state = ? WHERE id = ?", state, alert.Id)
+			So(err, ShouldBeNil)
+		}
+		for i := 0; i < 3; i++ {
+			insertWithState(1, models.AlertStateAlerting)
+		}
+		insertWithState(2, models.AlertStateAlerting)
+		insertWithState(2, models.AlertStateAlerting)
+		insertWithState(2, models.AlertStateOK)
+		insertWithState(3, models.AlertStateAlerting)
+		insertWithState(3, models.AlertStateOK)
+
+		Convey("Should return the orgs at or above the threshold, highest count first", func() {
+			query := &models.GetOrgsWithAlertStateCountAboveThresholdQuery{State: models.AlertStateAlerting, Threshold: 2}
+			So(GetOrgsWithAlertStateCountAboveThreshold(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(*query.Result[0], ShouldResemble, models.OrgAlertStateCount{OrgId: 1, Count: 3})
+			So(*query.Result[1], ShouldResemble, models.OrgAlertStateCount{OrgId: 2, Count: 2})
+		})
+
+		Convey("Should find nothing above the largest count", func() {
+			query := &models.GetOrgsWithAlertStateCountAboveThresholdQuery{State: models.AlertStateAlerting, Threshold: 4}
+			So(GetOrgsWithAlertStateCountAboveThreshold(query), ShouldBeNil)
+			So(query.Result, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestGetAlertNamesForDashboard(t *testing.T) {
+	Convey("Given alerts on several dashboards", t, func() {
+		InitTestDB(t)
+
+		dash := insertTestDashboard("dashboard", 1, 0, false)
+		other := insertTestDashboard("other", 1, 0, false)
+
+		alerts := insertTestPanelAlerts(1, dash.Id, "cpu", "memory")
+		cpu, memory := alerts[0], alerts[1]
+		insertTestPanelAlerts(1, other.Id, "disk")
+
+		Convey("Should return the names of the alerts of the dashboard ordered by name", func() {
+			query := &models.GetAlertNamesForDashboardQuery{OrgId: 1, DashboardId: dash.Id}
+			So(GetAlertNamesForDashboard(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(*query.Result[0], ShouldResemble, models.AlertNameDTO{Id: cpu.Id, Name: "cpu", PanelId: 1})
+			So(*query.Result[1], ShouldResemble, models.AlertNameDTO{Id: memory.Id, Name: "memory", PanelId: 2})
+		})
+
+		Convey("Should not return alerts of the dashboard from another org", func() {
+			query := &models.GetAlertNamesForDashboardQuery{OrgId: 2, DashboardId: dash.Id}
+			So(GetAlertNamesForDashboard(query), ShouldBeNil)
+			So(query.Result, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestGetAlertsByNewStateDateHour(t *testing.T) {
+	Convey("Given alerts that changed state at different hours", t, func() {
+		InitTestDB(t)
+		defer resetTimeNow()
+
+		hour := time.Date(2019, 3, 14, 10, 0, 0, 0, time.UTC)
+		changeAt := func(orgId int64, at time.Time) {
+			alert, err := insertTestAlert("alert", "", orgId, insertTestDashboard(at.String(), orgId, 0, false).Id, simplejson.New())
+			So(err, ShouldBeNil)
+			timeNow = func() time.Time { return at }
+			So(SetAlertState(&models.SetAlertStateCommand{AlertId: alert.Id, OrgId: orgId, State: models.AlertStateAlerting}), ShouldBeNil)
+		}
+		changeAt(1, hour.Add(-time.Minute))
+		changeAt(1, hour.Add(15*time.Minute))
+		changeAt(1, hour.Add(45*time.Minute))
+		changeAt(1, hour.Add(150*time.Minute))
+		changeAt(1, hour.Add(3*time.Hour))
+		changeAt(2, hour.Add(10*time.Minute))
+
+		Convey("Should count the changes per hour within the range", func() {
+			query := &models.GetAlertsByNewStateDateHourQuery{OrgId: 1, From: hour, To: hour.Add(3 * time.Hour)}
+			So(GetAlertsByNewStateDateHour(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.Result[0].HourStart.UTC(), ShouldEqual, hour)
+			So(query.Result[0].ChangeCount, ShouldEqual, 2)
+			So(query.Result[1].HourStart.UTC(), ShouldEqual, hour.Add(2*time.Hour))
+			So(query.Result[1].ChangeCount, ShouldEqual, 1)
+		})
+	})
+}
+
+func TestGetAlertsByConditionOperator(t *testing.T) {
+	Convey("Given alerts combining conditions with different operators", t, func() {
+		InitTestDB(t)
+
+		orSettings, _ := simplejson.NewJson([]byte(`{"conditions": [
+			{"operator": {"type": "and"}},
+			{"operator": {"type": "or"}}
+		]}`))
+		andSettings, _ := simplejson.NewJson([]byte(`{"conditions": [
+			{"operator": {"type": "or"}},
+			{}
+		]}`))
+		single, _ := simplejson.NewJson([]byte(`{"conditions": [{"operator": {"type": "or"}}]}`))
+
+		orAlert, err := insertTestAlert("or", "", 1, insertTestDashboard("or", 1, 0, false).Id, orSettings)
+		So(err, ShouldBeNil)
+		andAlert, err := insertTestAlert("and", "", 1, insertTestDashboard("and", 1, 0, false).Id, andSettings)
+		So(err, ShouldBeNil)
+		_, err = insertTestAlert("single", "", 1, insertTestDashboard("single", 1, 0, false).Id, single)
+		So(err, ShouldBeNil)
+
+		Convey("Should ignore the operator of the first condition", func() {
+			query := &models.GetAlertsByConditionOperatorQuery{OrgId: 1, Operator: "or"}
+			So(GetAlertsByConditionOperator(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Id, ShouldEqual, orAlert.Id)
+		})
+
+		Convey("Should default a missing operator to and", func() {
+			query := &models.GetAlertsByConditionOperatorQuery{OrgId: 1, Operator: "and"}
+			So(GetAlertsByConditionOperator(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Id, ShouldEqual, andAlert.Id)
+		})
+
+		Convey("Should reject unknown operators", func() {
+			query := &models.GetAlertsByConditionOperatorQuery{OrgId: 1, Operator: "xor"}
+			So(GetAlertsByConditionOperator(query), ShouldEqual, models.ErrInvalidConditionOperator)
+		})
+	})
+}
+
+func TestGetAlertStatesForPanels(t *testing.T) {
+	Convey("Given alerts on different panels", t, func() {
+		InitTestDB(t)
+
+		first := insertTestDashboard("first", 1, 0, false)
+		second := insertTestDashboard("second", 1, 0, false)
+		otherOrg := insertTestDashboard("other org", 2, 0, false)
+
+		onFirst := insertTestPanelAlerts(1, first.Id, "cpu", "memory")[0]
+		onSecond := insertTestPanelAlerts(1, second.Id, "cpu")[0]
+		insertTestPanelAlerts(2, otherOrg.Id, "cpu")
+
+		So(SetAlertState(&models.SetAlertStateCommand{AlertId: onSecond.Id, OrgId: 1, State: models.AlertStateAlerting}), ShouldBeNil)
+
+		Convey("Should return the states of the alerts on the panels of the org", func() {
+			query := &models.GetAlertStatesForPanelsQuery{OrgId: 1, PanelIds: []int64{1}}
+			So(GetAlertStatesForPanels(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+
+			states := make(map[int64]models.AlertStateType)
+			for _, state := range query.Result {
+				So(state.PanelId, ShouldEqual, 1)
+				states[state.Id] = state.State
+			}
+			So(states[onFirst.Id], ShouldEqual, models.AlertStateUnknown)
+			So(states[onSecond.Id], ShouldEqual, models.AlertStateAlerting)
+		})
+
+		Convey("Should find nothing without panel ids", func() {
+			query := &models.GetAlertStatesForPanelsQuery{OrgId: 1}
+			So(GetAlertStatesForPanels(query), ShouldBeNil)
+			So(query.Result, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestGetAlertNamingViolations(t *testing.T) {
+	Convey("Given alerts tagged with their service", t, func() {
+		InitTestDB(t)
+
+		tagged, _ := simplejson.NewJson([]byte(`{"alertRuleTags": {"service": "api"}}`))
+		otherTag, _ := simplejson.NewJson([]byte(`{"alertRuleTags": {"team": "api"}}`))
+
+		_, err := insertTestAlert("api latency", "", 1, insertTestDashboard("api latency", 1, 0, false).Id, tagged)
+		So(err, ShouldBeNil)
+		wrongPrefix, err := insertTestAlert("latency", "", 1, insertTestDashboard("latency", 1, 0, false).Id, tagged)
+		So(err, ShouldBeNil)
+		untagged, err := insertTestAlert("untagged", "", 1, insertTestDashboard("untagged", 1, 0, false).Id, otherTag)
+		So(err, ShouldBeNil)
+
+		Convey("Should report the alerts not named after their tag", func() {
+			query := &models.GetAlertNamingViolationsQuery{OrgId: 1, TagKey: "service"}
+			So(GetAlertNamingViolations(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.Result[0].Id, ShouldEqual, wrongPrefix.Id)
+			So(query.Result[0].ExpectedPrefix, ShouldEqual, "api")
+			So(query.Result[1].Id, ShouldEqual, untagged.Id)
+			So(query.Result[1].ExpectedPrefix, ShouldEqual, "")
+		})
+
+		Convey("Should require a tag key", func() {
+			query := &models.GetAlertNamingViolationsQuery{OrgId: 1}
+			So(GetAlertNamingViolations(query), ShouldEqual, models.ErrAlertNamingTagKeyRequired)
+		})
+	})
+}
+
+func TestGetAlertsByOrgFiringDuration(t *testing.T) {
+	Convey("Given alerts that started firing at different times", t, func() {
+		InitTestDB(t)
+		defer resetTimeNow()
+
+		now := time.Date(2019, 3, 14, 12, 0, 0, 0, time.UTC)
+		newest, err := insertTestAlert("newest", "", 1, insertTestDashboard("newest", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		oldest, err := insertTestAlert("oldest", "", 1, insertTestDashboard("oldest", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		ok, err := insertTestAlert("ok", "", 1, insertTestDashboard("ok", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		otherOrg, err := insertTestAlert("other org", "", 2, insertTestDashboard("other org", 2, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+
+		setState := func(alert *models.Alert, state models.AlertStateType, at time.Time) {
+			timeNow = func() time.Time { return at }
+			So(SetAlertState(&models.SetAlertStateCommand{AlertId: alert.Id, OrgId: alert.OrgId, State: state}), ShouldBeNil)
+		}
+		setState(newest, models.AlertStateAlerting, now.Add(-time.Hour))
+		setState(oldest, models.AlertStateAlerting, now.Add(-3*time.Hour))
+		setState(ok, models.AlertStateOK, now.Add(-4*time.Hour))
+		setState(otherOrg, models.AlertStateAlerting, now.Add(-5*time.Hour))
+		timeNow = func() time.Time { return now }
+
+		Convey("Should return the firing alerts of the org, longest firing first", func() {
+			query := &models.GetAlertsByOrgFiringDurationQuery{OrgId: 1}
+			So(GetAlertsByOrgFiringDuration(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(*query.Result[0], ShouldResemble, models.AlertFiringDurationDTO{AlertId: oldest.Id, AlertName: "oldest", FiringDuration: 3 * time.Hour})
+			So(*query.Result[1], ShouldResemble, models.AlertFiringDurationDTO{AlertId: newest.Id, AlertName: "newest", FiringDuration: time.Hour})
+		})
+	})
+}
+
+func TestGetAlertsBySettingsSize(t *testing.T) {
+	Convey("Given alerts with settings of different sizes", t, func() {
+		InitTestDB(t)
+
+		large := simplejson.New()
+		large.Set("description", strings.Repeat("x", 200))
+		medium := simplejson.New()
+		medium.Set("a", "b")
+
+		largeAlert, err := insertTestAlert("large", "", 1, insertTestDashboard("large", 1, 0, false).Id, large)
+		So(err, ShouldBeNil)
+		mediumAlert, err := insertTestAlert("medium", "", 1, insertTestDashboard("medium", 1, 0, false).Id, medium)
+		So(err, ShouldBeNil)
+		smallAlert, err := insertTestAlert("small", "", 1, insertTestDashboard("small", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+
+		Convey("Should return the alerts at least the size, largest first", func() {
+			query := &models.GetAlertsBySettingsSizeQuery{OrgId: 1, MinBytes: 1}
+			So(GetAlertsBySettingsSize(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 3)
+			So(query.Result[0].Id, ShouldEqual, largeAlert.Id)
+			So(query.Result[0].SettingsSizeBytes, ShouldBeGreaterThan, 200)
+			So(query.Result[1].Id, ShouldEqual, mediumAlert.Id)
+			So(query.Result[1].SettingsSizeBytes, ShouldEqual, len(`{"a":"b"}`))
+			So(query.Result[2].Id, ShouldEqual, smallAlert.Id)
+			So(query.Result[2].SettingsSizeBytes, ShouldEqual, len(`{}`))
+		})
+
+		Convey("Should leave out alerts below the size", func() {
+			query := &models.GetAlertsBySettingsSizeQuery{OrgId: 1, MinBytes: 200}
+			So(GetAlertsBySettingsSize(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Id, ShouldEqual, largeAlert.Id)
+		})
+	})
+}
+
+func TestGetAlertsWithBlankName(t *testing.T) {
+	Convey("Given alerts with blank and non blank names", t, func() {
+		InitTestDB(t)
+
+		empty, err := insertTestAlert("", "", 1, insertTestDashboard("empty", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		spaces, err := insertTestAlert("   ", "", 1, insertTestDashboard("spaces", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		_, err = insertTestAlert(" named ", "", 1, insertTestDashboard("named", 1, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		_, err = insertTestAlert("", "", 2, insertTestDashboard("other org", 2, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+
+		Convey("Should return the alerts of the org with empty or space only names", func() {
+			query := &models.GetAlertsWithBlankNameQuery{OrgId: 1}
+			So(GetAlertsWithBlankName(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.Result[0].Id, ShouldEqual, empty.Id)
+			So(query.Result[1].Id, ShouldEqual, spaces.Id)
+		})
+	})
+}
+
+func TestGetAlertsByOrgAndNotPaused(t *testing.T) {
+	Convey("Given paused and not paused alerts", t, func() {
+		InitTestDB(t)
+
+		alerts := make([]*models.Alert, 0)
+		for i := 0; i < 4; i++ {
+			alert, err := insertTestAlert(fmt.Sprintf("alert %d", i), "", 1, insertTestDashboard(fmt.Sprintf("dash %d", i), 1, 0, false).Id, simplejson.New())
+			So(err, ShouldBeNil)
+			alerts = append(alerts, alert)
+		}
+		_, err := insertTestAlert("other org", "", 2, insertTestDashboard("other org", 2, 0, false).Id, simplejson.New())
+		So(err, ShouldBeNil)
+		_, err = pauseAlert(1, alerts[1].Id, true)
+		So(err, ShouldBeNil)
+
+		Convey("Should page through the not paused alerts of the org", func() {
+			query := &models.GetAlertsByOrgAndNotPausedQuery{OrgId: 1, MaxAlertsPerFetch: 2}
+			So(GetAlertsByOrgAndNotPaused(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 2)
+			So(query.Result[0].Id, ShouldEqual, alerts[0].Id)
+			So(query.Result[1].Id, ShouldEqual, alerts[2].Id)
+			So(query.NextCursor, ShouldEqual, alerts[2].Id)
+
+			query.AfterAlertId = query.NextCursor
+			So(GetAlertsByOrgAndNotPaused(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Id, ShouldEqual, alerts[3].Id)
+			So(query.NextCursor, ShouldEqual, 0)
+		})
+
+		Convey("Should return all alerts in one chunk by default", func() {
+			query := &models.GetAlertsByOrgAndNotPausedQuery{OrgId: 1}
+			So(GetAlertsByOrgAndNotPaused(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 3)
+			So(query.NextCursor, ShouldEqual, 0)
+		})
+	})
+}
+
+func TestSilenceAlertsByFilter(t *testing.T) {
+	Convey("Given alerts in staging and production", t, func() {
+		InitTestDB(t)
+
+		staging, _ := simplejson.NewJson([]byte(`{"alertRuleTags": {"env": "staging"}}`))
+		prod, _ := simplejson.NewJson([]byte(`{"alertRuleTags": {"env": "prod"}}`))
+
+		stagingAlert, err := insertTestAlert("staging", "", 1, insertTestDashboard("first", 1, 0, false).Id, staging)
+		So(err, ShouldBeNil)
+		prodAlert, err := insertTestAlert("prod", "", 1, insertTestDashboard("second", 1, 0, false).Id, prod)
+		So(err, ShouldBeNil)
+
+		Convey("Should set the execution error state of matching alerts", func() {
+			cmd := &models.SetExecutionErrorStateCommand{
+				OrgId:               1,
+				Filter:              &models.GetAlertsQuery{Tags: []string{"env:prod"}},
+				ExecutionErrorState: models.ExecutionErrorSetAlerting,
+			}
+			So(SetExecutionErrorState(cmd), ShouldBeNil)
+			So(cmd.ResultCount, ShouldEqual, 1)
+
+			alert, _ := getAlertById(prodAlert.Id)
+			So(alert.Settings.Get("executionErrorState").MustString(), ShouldEqual, "alerting")
+			So(alert.Settings.Get("alertRuleTags").Get("env").MustString(), ShouldEqual, "prod")
+			alert, _ = getAlertById(stagingAlert.Id)
+			So(alert.Settings.Get("executionErrorState").MustString(), ShouldEqual, "")
+		})
+
+		Convey("Should set the execution error state in the panel json", func() {
+			dash, _ := insertTestAlertPanel("panel", 1, `{"name": "panel", "alertRuleTags": {"env": "prod"}}`)
+
+			cmd := &models.SetExecutionErrorStateCommand{
+				OrgId:               1,
+				Filter:              &models.GetAlertsQuery{Tags: []string{"env:prod"}},
+				ExecutionErrorState: models.ExecutionErrorKeepState,
+			}
+			So(SetExecutionErrorState(cmd), ShouldBeNil)
+			So(cmd.ResultCount, ShouldEqual, 2)
+			So(getTestPanelAlert(dash.Id).Get("executionErrorState").MustString(), ShouldEqual, "keep_state")
+		})
+
+		Convey("Should reject an invalid execution error state", func() {
+			cmd := &models.SetExecutionErrorStateCommand{OrgId: 1, ExecutionErrorState: "no_data"}
+			So(SetExecutionErrorState(cmd), ShouldEqual, models.ErrInvalidExecutionErrorState)
+		})
+
+		Convey("Should only silence matching alerts", func() {
+			cmd := &models.SilenceAlertsByFilterCommand{
+				OrgId:    1,
+				Filter:   &models.GetAlertsQuery{Tags: []string{"env:staging"}},
+				Silenced: true,
+			}
+			So(SilenceAlertsByFilter(cmd), ShouldBeNil)
+			So(cmd.ResultCount, ShouldEqual, 1)
+
+			alert, _ := getAlertById(stagingAlert.Id)
+			So(alert.Silenced, ShouldBeTrue)
+			alert, _ = getAlertById(prodAlert.Id)
+			So(alert.Silenced, ShouldBeFalse)
+		})
+	})
+}
+
+func TestGetUnmonitoredServices(t *testing.T) {
+	Convey("Given alerts of two services", t, func() {
+		InitTestDB(t)
+
+		checkout, _ := simplejson.NewJson([]byte(`{"alertRuleTags": {"service": "checkout"}}`))
+		search, _ := simplejson.NewJson([]byte(`{"alertRuleTags": {"service": "search"}}`))
+
+		first, _ := insertTestAlert("checkout latency", "", 1, insertTestDashboard("first", 1, 0, false).Id, checkout)
+		second, _ := insertTestAlert("checkout errors", "", 1, insertTestDashboard("second", 1, 0, false).Id, checkout)
+		third, _ := insertTestAlert("search latency", "", 1, insertTestDashboard("third", 1, 0, false).Id, search)
+		_, _ = insertTestAlert("search errors", "", 1, insertTestDashboard("fourth", 1, 0, false).Id, search)
+
+		_, _ = pauseAlert(1, first.Id, true)
+		_, _ = pauseAlert(1, second.Id, true)
+		_, _ = pauseAlert(1, third.Id, true)
+
+		Convey("Should return services whose alerts are all paused", func() {
+			query := &models.GetUnmonitoredServicesQuery{OrgId: 1, TagKey: "service"}
+			So(GetUnmonitoredServices(query), ShouldBeNil)
+			So(query.Result, ShouldHaveLength, 1)
+			So(query.Result[0].Service, ShouldEqual, "checkout")
+			So(query.Result[0].AlertCount, ShouldEqual, 2)
+		})
+	})
+}
+
+func pauseAlert(orgId int64, alertId int64, pauseState bool) (int64, error) {
+	cmd := &models.PauseAlertCommand{
+		OrgId:    orgId,
+		AlertIds: []int64{alertId},
+		Paused:   pauseState,
+	}
+	err := PauseAlert(cmd)
+	So(err, ShouldBeNil)
+	return cmd.ResultCount, err
+}
+func insertTestAlert(title string, message string, orgId int64, dashId int64, settings *simplejson.Json) (*models.Alert, error) {
+	items := []*models.Alert{
+		{
+			PanelId:     1,
+			DashboardId: dashId,
+			OrgId:       orgId,
+			Name:        title,
+			Message:     message,
+			Settings:    settings,
+			Frequency:   1,
+		},
+	}
+
+	cmd := models.SaveAlertsCommand{
+		Alerts:      items,
+		DashboardId: dashId,
+		OrgId:       orgId,
+		UserId:      1,
+	}
+
+	err := SaveAlerts(&cmd)
+	return cmd.Alerts[0], err
+}
+
+// insertTestPanelAlerts saves an alert per name on the dashboard, on the
+// panels with ids 1, 2, ...
+func insertTestPanelAlerts(orgId int64, dashId int64, names ...string) []*models.Alert {
+	cmd := &models.SaveAlertsCommand{
+		DashboardId: dashId,
+		OrgId:       orgId,
+		UserId:      1,
+	}
+	for i, name := range names {
+		cmd.Alerts = append(cmd.Alerts, &models.Alert{
+			PanelId:     int64(i + 1),
+			DashboardId: dashId,
+			OrgId:       orgId,
+			Name:        name,
+			Settings:    simplejson.New(),
+			Frequency:   1,
+		})
+	}
+
+	So(SaveAlerts(cmd), ShouldBeNil)
+	return cmd.Alerts
+}
+
+// insertTestAlertPanel saves a dashboard with a single panel holding the
+// alert json and the alert extracted from it.
+func insertTestAlertPanel(title string, orgId int64, alertJson string) (*models.Dashboard, *models.Alert) {
+	panelAlert, err := simplejson.NewJson([]byte(alertJson))
+	So(err, ShouldBeNil)
+
+	cmd := models.SaveDashboardCommand{
+		OrgId: orgId,
+		Dashboard: simplejson.NewFromAny(map[string]interface{}{
+			"id":    nil,
+			"title": title,
+			"panels": []interface{}{
+				map[string]interface{}{"id": 1, "alert": panelAlert.Interface()},
+			},
+		}),
+	}
+	So(SaveDashboard(&cmd), ShouldBeNil)
+
+	settings, err := simplejson.NewJson([]byte(alertJson))
+	So(err, ShouldBeNil)
+	alert, err := insertTestAlert(settings.Get("name").MustString(title), "", orgId, cmd.Result.Id, settings)
+	So(err, ShouldBeNil)
+
+	return cmd.Result, alert
+}
+
+func getTestDashboard(id int64) *models.Dashboard {
+	dash := &models.Dashboard{}
+	has, err := x.ID(id).Get(dash)
+	So(err, ShouldBeNil)
+	So(has, ShouldBeTrue)
+	return dash
+}
+
+// getTestPanelAlert returns the alert json of the first panel of a
+// dashboard saved by insertTestAlertPanel.
+func getTestPanelAlert(dashboardId int64) *simplejson.Json {
+	return getTestDashboard(dashboardId).Data.Get("panels").GetIndex(0).Get("alert")
 }
 
 func getAlertById(id int64) (*models.Alert, error) {
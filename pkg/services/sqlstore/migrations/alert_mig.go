@@ -175,4 +175,58 @@ func addAlertMigrations(mg *Migrator) {
 	// change column type of alert.settings
 	mg.AddMigration("alter alert.settings to mediumtext", NewRawSqlMigration("").
 		Mysql("ALTER TABLE alert MODIFY settings MEDIUMTEXT;"))
+
+	mg.AddMigration("Add eval_timeout to alert table", NewAddColumnMigration(alertV1, &Column{
+		Name: "eval_timeout", Type: DB_BigInt, Nullable: true,
+	}))
+
+	mg.AddMigration("add index alert org_id & new_state_date", NewAddIndexMigration(alertV1, &Index{
+		Cols: []string{"org_id", "new_state_date"}, Type: IndexType,
+	}))
+
+	mg.AddMigration("Add pause_reason to alert table", NewAddColumnMigration(alertV1, &Column{
+		Name: "pause_reason", Type: DB_NVarchar, Length: 255, Nullable: true,
+	}))
+
+	mg.AddMigration("Add paused_by to alert table", NewAddColumnMigration(alertV1, &Column{
+		Name: "paused_by", Type: DB_BigInt, Nullable: true,
+	}))
+
+	alert_maintenance_window := Table{
+		Name: "alert_maintenance_window",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "starts", Type: DB_DateTime, Nullable: false},
+			{Name: "ends", Type: DB_DateTime, Nullable: false},
+			{Name: "state", Type: DB_NVarchar, Length: 50, Nullable: false},
+			{Name: "created_by", Type: DB_BigInt, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id"}, Type: IndexType},
+			{Cols: []string{"state", "starts"}, Type: IndexType},
+		},
+	}
+
+	mg.AddMigration("create alert_maintenance_window table v1", NewAddTableMigration(alert_maintenance_window))
+	addTableIndicesMigrations(mg, "v1", alert_maintenance_window)
+
+	alert_maintenance_window_alert := Table{
+		Name: "alert_maintenance_window_alert",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "window_id", Type: DB_BigInt, Nullable: false},
+			{Name: "alert_id", Type: DB_BigInt, Nullable: false},
+			{Name: "paused", Type: DB_Bool, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"window_id", "alert_id"}, Type: UniqueIndex},
+			{Cols: []string{"alert_id"}, Type: IndexType},
+		},
+	}
+
+	mg.AddMigration("create alert_maintenance_window_alert table v1", NewAddTableMigration(alert_maintenance_window_alert))
+	addTableIndicesMigrations(mg, "v1", alert_maintenance_window_alert)
 }
@@ -28,6 +28,16 @@ func (sb *SqlBuilder) AddParams(params ...interface{}) {
 	sb.params = append(sb.params, params...)
 }
 
+// likeEscape is the ESCAPE clause to use with patterns from escapeLikePattern.
+const likeEscape = ` ESCAPE '!'`
+
+// escapeLikePattern escapes the LIKE wildcards in s so it matches literally.
+// It has to be used together with likeEscape since not all databases have a
+// default escape character.
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
 func (sb *SqlBuilder) writeDashboardPermissionFilter(user *models.SignedInUser, permission models.PermissionType) {
 	if user.OrgRole == models.ROLE_ADMIN {
 		return
@@ -89,3 +99,23 @@ func (sb *SqlBuilder) writeDashboardPermissionFilter(user *models.SignedInUser,
 	sb.params = append(sb.params, user.OrgId, permission, user.UserId)
 	sb.params = append(sb.params, okRoles...)
 }
+
+// writeTeamDashboardPermissionFilter limits the result to dashboards where
+// the team has been granted at least permission, either on the dashboard
+// itself or on its folder. Permissions granted to roles are not considered.
+func (sb *SqlBuilder) writeTeamDashboardPermissionFilter(orgId int64, teamId int64, permission models.PermissionType) {
+	sb.sql.WriteString(` AND
+	dashboard.id IN (
+		SELECT d.id
+			FROM dashboard AS d
+			INNER JOIN dashboard_acl AS da ON
+				da.dashboard_id = d.id OR
+				da.dashboard_id = d.folder_id
+			WHERE
+				d.org_id = ? AND
+				da.team_id = ? AND
+				da.permission >= ?
+	)`)
+
+	sb.params = append(sb.params, orgId, teamId, permission)
+}